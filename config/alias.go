@@ -0,0 +1,116 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "sync"
+
+// Alias declares a deprecated name of an option, or of a whole section if both
+// options are empty.
+type Alias struct {
+	OldSection, OldOption string // Deprecated name
+	Section, Option       string // Current name
+}
+
+// Alias and the call of the deprecation hook for it, done once.
+type alias struct {
+	Alias
+	warned sync.Once
+}
+
+// DeprecationHook is called the first time an option is found under a
+// deprecated name.
+type DeprecationHook func(alias Alias)
+
+// AddAlias declares that the option (or the section, if both options are
+// empty) formerly named oldSection, oldOption is now named section, option.
+// Lookups of the current name, including those of Decode, transparently fall
+// back to the deprecated one when the option is not set under its current
+// name; MigrateAliases renames the options for good.
+func (self *Config) AddAlias(oldSection, oldOption, section, option string) {
+	if (oldOption == "") != (option == "") {
+		panic("alias of an option to a section")
+	}
+	if oldSection == "" {
+		oldSection = _DEFAULT_SECTION
+	}
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	self.aliases = append(self.aliases, &alias{Alias: Alias{oldSection, oldOption, section, option}})
+	self.changed()
+}
+
+// SetDeprecationHook sets the function called the first time an option is
+// found under a deprecated name, e.g. to log a warning; nil removes it.
+func (self *Config) SetDeprecationHook(hook DeprecationHook) {
+	self.deprecationHook = hook
+}
+
+// MigrateAliases renames the sections and options set under a deprecated name
+// to their current one, keeping their order. An option also set under its
+// current name is removed instead. The migrated configuration can then be
+// saved with WriteFile.
+//
+// It returns the aliases which were applied.
+func (self *Config) MigrateAliases() (applied []Alias) {
+	for _, a := range self.aliases {
+		if a.OldOption == "" {
+			if !self.HasSection(a.OldSection) || a.OldSection == _DEFAULT_SECTION {
+				continue
+			}
+
+			if !self.HasSection(a.Section) {
+				self.renameSection(a.OldSection, a.Section)
+			} else {
+				for _, option := range self.optionNames(a.OldSection) {
+					self.moveOption(a.OldSection, option, a.Section, option)
+				}
+				self.RemoveSection(a.OldSection)
+			}
+			applied = append(applied, a.Alias)
+			continue
+		}
+
+		if _, ok := self.data[a.OldSection][a.OldOption]; !ok {
+			continue
+		}
+		self.moveOption(a.OldSection, a.OldOption, a.Section, a.Option)
+		applied = append(applied, a.Alias)
+	}
+
+	return applied
+}
+
+// === Utility
+// ===
+
+// lookupAlias returns the value held for the option under a deprecated name.
+func (self *Config) lookupAlias(section string, option string) (*tValue, bool) {
+	for _, a := range self.aliases {
+		var tv *tValue
+		var ok bool
+
+		switch {
+		case a.Option == "" && a.Section == section:
+			tv, ok = self.data[a.OldSection][option]
+		case a.Option == option && a.Section == section:
+			tv, ok = self.data[a.OldSection][a.OldOption]
+		}
+		if !ok {
+			continue
+		}
+
+		if hook := self.deprecationHook; hook != nil {
+			a.warned.Do(func() { hook(a.Alias) })
+		}
+		return tv, true
+	}
+	return nil, false
+}
@@ -11,9 +11,17 @@ package config
 
 import (
 	"bufio"
+	"errors"
+	"io"
+	"net/netip"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const tmp = "/tmp/__config_test.go__garbage"
@@ -21,7 +29,7 @@ const tmp = "/tmp/__config_test.go__garbage"
 func testGet(t *testing.T, c *Config, section string, option string,
 	expected interface{}) {
 	ok := false
-	switch _ := expected.(type) {
+	switch expected.(type) {
 	case string:
 		v, _ := c.String(section, option)
 		if v == expected.(string) {
@@ -182,7 +190,7 @@ func TestInMemory(t *testing.T) {
 	_, err = c.String(_DEFAULT_SECTION, "opt1")
 	if err == nil {
 		t.Errorf("String failure: no error for cycle")
-	} else if strings.Index(err.String(), "cycle") < 0 {
+	} else if strings.Index(err.Error(), "cycle") < 0 {
 		t.Errorf("String failure: incorrect error for cycle")
 	}
 }
@@ -213,7 +221,7 @@ func TestReadFile(t *testing.T) {
 
 	c, err := ReadDefault(tmp)
 	if err != nil {
-		t.Fatalf("ReadDefault failure: " + err.String())
+		t.Fatalf("ReadDefault failure: %v", err)
 	}
 
 	// check number of sections
@@ -255,7 +263,7 @@ func TestWriteReadFile(t *testing.T) {
 	// read back file and test
 	cr, err := ReadDefault(tmp)
 	if err != nil {
-		t.Fatalf("ReadDefault failure: " + err.String())
+		t.Fatalf("ReadDefault failure: %v", err)
 	}
 
 	testGet(t, cr, "First-Section", "option1", "value option1")
@@ -265,3 +273,1462 @@ func TestWriteReadFile(t *testing.T) {
 
 	defer os.Remove(tmp)
 }
+
+// Tests multi-valued options, in-memory and written back to a file.
+func TestMultiValue(t *testing.T) {
+	c := NewDefault()
+	c.SetMultiValue(true)
+
+	c.AddOption("remote", "fetch", "+refs/heads/*")
+	c.AddOption("remote", "url", "%(host)s/repo")
+	c.AddOption("remote", "fetch", "+refs/tags/*")
+	c.AddOption(_DEFAULT_SECTION, "host", "example.com")
+
+	values, err := c.Values("remote", "fetch")
+	if err != nil || len(values) != 2 || values[0] != "+refs/heads/*" ||
+		values[1] != "+refs/tags/*" {
+		t.Errorf("Values failure: got %q, %v", values, err)
+	}
+	testGet(t, c, "remote", "fetch", "+refs/tags/*") // last value wins
+
+	if !c.RemoveValue("remote", "fetch", "+refs/tags/*") {
+		t.Errorf("RemoveValue failure: false on existing value")
+	}
+	if c.RemoveValue("remote", "fetch", "+refs/tags/*") {
+		t.Errorf("RemoveValue failure: true on removed value")
+	}
+	testGet(t, c, "remote", "fetch", "+refs/heads/*")
+
+	c.SetValues("remote", "push", []string{"a", "b", "c"})
+	c.AppendValue("remote", "push", "d")
+
+	c.WriteFile(tmp, 0644, "")
+	defer os.Remove(tmp)
+
+	cr, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	testGet(t, cr, "remote", "push", "d") // repeated keys overwritten
+
+	cr = NewDefault()
+	cr.SetMultiValue(true)
+	if err = cr.ReadFile(tmp); err != nil {
+		t.Fatalf("ReadFile failure: %v", err)
+	}
+	os.WriteFile(tmp, []byte("[remote]\npush: e\n"), 0644)
+	if err = cr.ReadFile(tmp); err != nil {
+		t.Fatalf("ReadFile failure: %v", err)
+	}
+
+	values, _ = cr.Values("remote", "push")
+	if strings.Join(values, ",") != "a,b,c,d,e" {
+		t.Errorf("Values failure: got %q", values)
+	}
+	testGet(t, cr, "remote", "url", "example.com/repo")
+}
+
+// Tests list and map values, written and read back.
+func TestListMap(t *testing.T) {
+	c := NewDefault()
+
+	os.WriteFile(tmp, []byte("[m]\nweights: a=1,\n  b=2\n\tc=3\n# end\n  d=4\n"+
+		"[remote \"origin\"]\n\turl = a\n\tfetch = b\n"), 0644)
+	cr, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	if !cr.HasOption("m", "b") { // indented options by default
+		t.Errorf("ReadDefault failure: indented option read as continuation")
+	}
+
+	for i := 0; i < 2; i++ { // read, then written and read back
+		cr = NewDefault()
+		cr.SetIndentedContinuation(true)
+		if err = cr.ReadFile(tmp); err != nil {
+			t.Fatalf("ReadFile failure: %v", err)
+		}
+		if m, err := cr.Map("m", "weights"); err != nil || len(m) != 3 || m["b"] != "2" || m["c"] != "3" || !cr.HasOption("m", "d") {
+			t.Errorf("Map failure: got %v, %v", m, err)
+		}
+		if o := cr.optionNames(Subsection("remote", "origin")); strings.Join(o, ",") != "url,fetch" {
+			t.Errorf("ReadFile failure: got options %v", o)
+		}
+		cr.WriteFile(tmp, 0644, "")
+	}
+	os.Remove(tmp)
+
+	c.AddOption("lists", "servers", "alpha, beta,\n\"gamma, delta\"")
+	list, err := c.List("lists", "servers")
+	if err != nil || strings.Join(list, "|") != "alpha|beta|gamma, delta" {
+		t.Errorf("List failure: got %q, %v", list, err)
+	}
+
+	c.AddOption("lists", "unterminated", "a, \"b")
+	if _, err = c.List("lists", "unterminated"); err == nil {
+		t.Errorf("List failure: no error for unterminated quote")
+	}
+
+	items := []string{"plain", " spaced ", "a, b", `quote " and \ `, "# x ; y", ""}
+	c.AddList("lists", "items", items)
+	c.AddIntList("lists", "ints", []int{1, -2, 3})
+	c.AddMap("lists", "map", map[string]string{"b": "2", "a=x": "1, y", "c": ""})
+
+	c.WriteFile(tmp, 0644, "")
+	defer os.Remove(tmp)
+
+	cr, err = ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	list, err = cr.List("lists", "items")
+	if err != nil || len(list) != len(items) {
+		t.Fatalf("List failure: got %q, %v", list, err)
+	}
+	for i := range items {
+		if list[i] != items[i] {
+			t.Errorf("List failure: got %q, expected %q", list[i], items[i])
+		}
+	}
+
+	ints, err := cr.IntList("lists", "ints")
+	if err != nil || len(ints) != 3 || ints[1] != -2 {
+		t.Errorf("IntList failure: got %v, %v", ints, err)
+	}
+
+	m, err := cr.Map("lists", "map")
+	if err != nil || len(m) != 3 || m["a=x"] != "1, y" || m["b"] != "2" || m["c"] != "" {
+		t.Errorf("Map failure: got %q, %v", m, err)
+	}
+}
+
+// Tests duration, time and byte size values.
+func TestDurationTimeSize(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("s", "timeout", "1h30m")
+	c.AddOption("s", "since", "2010-09-15T10:00:00Z")
+	c.AddOption("s", "day", "2010-09-15")
+	c.AddOption("s", "buffer", "512MiB")
+	c.AddOption("s", "disk", "1.5 GB")
+	c.AddOption("s", "huge", "20EiB")
+	c.AddOption("s", "bad", "12 parsecs")
+
+	if d, err := c.Duration("s", "timeout"); err != nil || d != 90*time.Minute {
+		t.Errorf("Duration failure: got %v, %v", d, err)
+	}
+
+	tm, err := c.Time("s", "since")
+	if err != nil || !tm.Equal(time.Date(2010, 9, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time failure: got %v, %v", tm, err)
+	}
+	if _, err = c.Time("s", "day"); err == nil {
+		t.Errorf("Time failure: no error for unknown layout")
+	}
+	c.SetTimeLayouts(time.RFC3339, "2006-01-02")
+	if _, err = c.Time("s", "day"); err != nil {
+		t.Errorf("Time failure: %v", err)
+	}
+
+	for option, expected := range map[string]uint64{"buffer": 512 << 20, "disk": 1500000000} {
+		if n, err := c.ByteSize("s", option); err != nil || n != expected {
+			t.Errorf("ByteSize failure: got %d, %v", n, err)
+		}
+	}
+	for _, option := range []string{"huge", "bad", "timeout"} {
+		_, err = c.ByteSize("s", option)
+		if e, ok := err.(*ConversionError); !ok || e.Section != "s" || e.Option != option {
+			t.Errorf("ByteSize failure: got error %v", err)
+		}
+	}
+}
+
+// Tests the integer family with base prefixes, overflows and bounds.
+func TestIntegers(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("i", "hex", "0x1F")
+	c.AddOption("i", "mode", "0o755")
+	c.AddOption("i", "big", "1_000_000")
+	c.AddOption("i", "negative", "-1")
+
+	if v, err := c.Int64("i", "hex"); err != nil || v != 31 {
+		t.Errorf("Int64 failure: got %d, %v", v, err)
+	}
+	if v, err := c.Uint32("i", "mode"); err != nil || v != 0755 {
+		t.Errorf("Uint32 failure: got %d, %v", v, err)
+	}
+	if v, err := c.Int32("i", "big"); err != nil || v != 1000000 {
+		t.Errorf("Int32 failure: got %d, %v", v, err)
+	}
+
+	if _, err := c.Int16("i", "big"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Int16 failure: got error %v", err)
+	}
+	if _, err := c.Uint64("i", "negative"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Uint64 failure: got error %v", err)
+	}
+
+	if _, err := c.IntRange("i", "hex", 0, 31); err != nil {
+		t.Errorf("IntRange failure: %v", err)
+	}
+	_, err := c.IntRange("i", "hex", 0, 30)
+	if e, ok := err.(*ConversionError); !ok || !errors.Is(e, strconv.ErrRange) {
+		t.Errorf("IntRange failure: got error %v", err)
+	}
+}
+
+// Tests network values, unfolded before being parsed.
+func TestNetwork(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("", "protocol", "https://")
+	c.AddOption("", "host", "www.example.com")
+	c.AddOption("n", "url", "%(protocol)s%(host)s/some/path")
+	c.AddOption("n", "relative", "/some/path")
+	c.AddOption("n", "ip", "fe80::1%eth0")
+	c.AddOption("n", "cidr", "10.0.0.0/8")
+	c.AddOption("n", "listen", "%(host)s:8080")
+	c.AddOption("n", "bad-port", "%(host)s:80808")
+
+	if u, err := c.URL("n", "url"); err != nil || u.Host != "www.example.com" {
+		t.Errorf("URL failure: got %v, %v", u, err)
+	}
+	if _, err := c.URL("n", "relative"); err == nil {
+		t.Errorf("URL failure: no error for relative URL")
+	}
+	if a, err := c.Addr("n", "ip"); err != nil || a.Zone() != "eth0" {
+		t.Errorf("Addr failure: got %v, %v", a, err)
+	}
+	if _, err := c.IP("n", "cidr"); err == nil {
+		t.Errorf("IP failure: no error for CIDR prefix")
+	}
+	if p, err := c.Prefix("n", "cidr"); err != nil || p.Bits() != 8 {
+		t.Errorf("Prefix failure: got %v, %v", p, err)
+	}
+	if h, p, err := c.HostPort("n", "listen"); err != nil || h != "www.example.com" || p != 8080 {
+		t.Errorf("HostPort failure: got %s %d, %v", h, p, err)
+	}
+	if _, _, err := c.HostPort("n", "bad-port"); err == nil {
+		t.Errorf("HostPort failure: no error for invalid port")
+	}
+}
+
+// Tests getters with a default value and the registry of default values.
+func TestDefaults(t *testing.T) {
+	d := NewDefaults()
+	d.Add("server", "port", "8080")
+	d.Add("", "timeout", "5s")
+
+	c := NewWithDefaults(d)
+	c.AddOption("server", "workers", "many")
+	c.AddOption("server", "debug", "on")
+
+	if v := c.IntDefault("server", "port", 80); v != 8080 {
+		t.Errorf("IntDefault failure: got %d from registry", v)
+	}
+	if v := c.IntDefault("server", "missing", 80); v != 80 {
+		t.Errorf("IntDefault failure: got %d for missing option", v)
+	}
+	if v := c.DurationDefault(_DEFAULT_SECTION, "timeout", time.Second); v != 5*time.Second {
+		t.Errorf("DurationDefault failure: got %v", v)
+	}
+	if v := c.BoolDefault("server", "debug", false); !v {
+		t.Errorf("BoolDefault failure: got %v", v)
+	}
+	if len(c.Diagnostics()) != 0 {
+		t.Errorf("Diagnostics failure: got %v", c.Diagnostics())
+	}
+
+	if v := c.IntDefault("server", "workers", 4); v != 4 {
+		t.Errorf("IntDefault failure: got %d for malformed value", v)
+	}
+	if diags := c.Diagnostics(); len(diags) != 1 {
+		t.Errorf("Diagnostics failure: got %v", diags)
+	} else if _, ok := diags[0].(*ConversionError); !ok {
+		t.Errorf("Diagnostics failure: got %T", diags[0])
+	}
+	c.ClearDiagnostics()
+	if len(c.Diagnostics()) != 0 {
+		t.Errorf("ClearDiagnostics failure")
+	}
+}
+
+type testLevel int
+
+// Tests custom converters, Value and Decode.
+func TestConvertDecode(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("", "host", "www.example.com")
+	c.AddOption("", "level", "debug")
+	c.AddOption("service-1", "url", "https://%(host)s/some/path")
+	c.AddOption("service-1", "maxclients", "0x10")
+	c.AddOption("service-1", "timeout", "30s")
+	c.AddOption("service-1", "addr", "127.0.0.1")
+	c.AddOption("service-1", "ports", "80, 443")
+	c.AddOption("service-1", "weights", "a=1.5, b=2")
+	c.AddOption("bad", "maxclients", "lots")
+	c.AddOption("bad", "level", "loud")
+
+	c.RegisterConverter(reflect.TypeOf(testLevel(0)), func(s string) (interface{}, error) {
+		switch s {
+		case "debug":
+			return testLevel(1), nil
+		case "info":
+			return testLevel(2), nil
+		}
+		return nil, errors.New("unknown level")
+	})
+
+	var level testLevel
+	if err := c.Value(_DEFAULT_SECTION, "level", &level); err != nil || level != 1 {
+		t.Errorf("Value failure: got %v, %v", level, err)
+	}
+
+	type service struct {
+		URL        *url.URL           `config:"url"`
+		MaxClients uint16             `config:"maxclients"`
+		Timeout    time.Duration      `config:"timeout"`
+		Addr       netip.Addr         `config:"addr"`
+		Ports      []int              `config:"ports"`
+		Weights    map[string]float64 `config:"weights"`
+		Missing    string
+		Level      testLevel
+		Skipped    string `config:"-"`
+	}
+	var settings struct {
+		Host    string
+		Level   testLevel
+		Service service `config:"service-1"`
+		Bad     service
+	}
+	settings.Service.Missing = "unchanged"
+
+	err := c.Decode(&settings)
+	s := settings.Service
+	if settings.Host != "www.example.com" || settings.Level != 1 ||
+		s.URL.String() != "https://www.example.com/some/path" ||
+		s.MaxClients != 16 || s.Timeout != 30*time.Second ||
+		s.Addr.String() != "127.0.0.1" || len(s.Ports) != 2 || s.Ports[1] != 443 ||
+		s.Weights["a"] != 1.5 || s.Missing != "unchanged" {
+		t.Errorf("Decode failure: got %+v", settings)
+	}
+
+	var e *ConversionError
+	if !errors.As(err, &e) || e.Section != "bad" {
+		t.Errorf("Decode failure: got error %v", err)
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 2 {
+		t.Errorf("Decode failure: got %d errors instead of 2", n)
+	}
+}
+
+// Tests the generic getters.
+func TestGet(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("g", "count", "010")
+	c.AddOption("g", "mode", "0o755")
+	c.AddOption("g", "timeouts", "1s, 1m")
+	c.AddOption("g", "addr", "::1")
+	c.AddOption("g", "ratio", "half")
+
+	c.AddOption("g", "mask", "0x10")
+	c.AddOption("g", "big", "1_000")
+	for option, expected := range map[string]int{"count": 10, "mask": 16, "big": 1000} {
+		v, err := c.Int("g", option)
+		gv, _ := Get[int](c, "g", option)
+		v64, _ := Get[int64](c, "g", option)
+		list, _ := c.IntList("g", option)
+		glist, _ := Get[[]int](c, "g", option)
+		if err != nil || v != expected || gv != v || int(v64) != v || c.IntDefault("g", option, 0) != v ||
+			len(list) != 1 || list[0] != v || len(glist) != 1 || glist[0] != v {
+			t.Errorf("integer syntax failure for %s: got %d, %v, %d, %d, %v, %v", option, v, err, gv, v64, list, glist)
+		}
+	}
+	if v, err := Get[uint32](c, "g", "mode"); err != nil || v != 0755 {
+		t.Errorf("Get[uint32] failure: got %d, %v", v, err)
+	}
+	if v, err := Get[[]time.Duration](c, "g", "timeouts"); err != nil || len(v) != 2 || v[1] != time.Minute {
+		t.Errorf("Get[[]time.Duration] failure: got %v, %v", v, err)
+	}
+	if v, err := Get[netip.Addr](c, "g", "addr"); err != nil || !v.Is6() {
+		t.Errorf("Get[netip.Addr] failure: got %v, %v", v, err)
+	}
+	if _, err := Get[string](c, "g", "missing"); err == nil {
+		t.Errorf("Get[string] failure: no error for missing option")
+	}
+
+	if v := GetDefault(c, "g", "missing", 0.5); v != 0.5 || len(c.Diagnostics()) != 0 {
+		t.Errorf("GetDefault failure: got %v", v)
+	}
+	if v := GetDefault(c, "g", "ratio", 0.5); v != 0.5 || len(c.Diagnostics()) != 1 {
+		t.Errorf("GetDefault failure: got %v, %v", v, c.Diagnostics())
+	}
+}
+
+// Tests custom and strict bool vocabularies.
+func TestBoolStrings(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("b", "short", "t")
+	c.AddOption("b", "feature", "Enabled")
+
+	testGet(t, c, "b", "short", true)
+	if _, err := c.Bool("b", "feature"); err == nil {
+		t.Errorf("Bool failure: no error for unknown string")
+	}
+
+	c.SetBoolStrings(StrictBoolStrings)
+	if _, err := c.Bool("b", "short"); err == nil {
+		t.Errorf("Bool failure: no error for ambiguous string in strict mode")
+	}
+
+	c.SetBoolStrings(BoolStrings{
+		True:  []string{"enabled", "true"},
+		False: []string{"disabled", "false"},
+	})
+	testGet(t, c, "b", "feature", true)
+
+	c.AddBool("b", "other", false)
+	if v, _ := c.RawString("b", "other"); v != "disabled" {
+		t.Errorf("AddBool failure: got %q", v)
+	}
+}
+
+// Tests that typed setters are read back identically by the typed getters.
+func TestTypedSetters(t *testing.T) {
+	c := NewDefault()
+	now := time.Date(2010, 9, 15, 10, 0, 0, 123456789, time.FixedZone("", 3600))
+	u, _ := url.Parse("https://www.example.com/some/path?q=1#top")
+
+	c.AddInt("s", "int", -42)
+	c.AddInt64("s", "int64", -1<<62)
+	c.AddUint64("s", "uint64", 1<<63)
+	c.AddFloat("s", "float", 0.1)
+	c.AddDuration("s", "duration", 90*time.Minute)
+	c.AddTime("s", "time", now)
+	c.AddByteSize("s", "mib", 512<<20)
+	c.AddByteSize("s", "mb", 1500000000)
+	c.AddURL("s", "url", u)
+	c.AddHostPort("s", "hostport", "::1", 8080)
+
+	c.WriteFile(tmp, 0644, "")
+	defer os.Remove(tmp)
+
+	cr, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	if v, _ := cr.Int("s", "int"); v != -42 {
+		t.Errorf("AddInt failure: got %d", v)
+	}
+	if v, _ := cr.Int64("s", "int64"); v != -1<<62 {
+		t.Errorf("AddInt64 failure: got %d", v)
+	}
+	if v, _ := cr.Uint64("s", "uint64"); v != 1<<63 {
+		t.Errorf("AddUint64 failure: got %d", v)
+	}
+	if v, _ := cr.Float("s", "float"); v != 0.1 {
+		t.Errorf("AddFloat failure: got %v", v)
+	}
+	if v, _ := cr.Duration("s", "duration"); v != 90*time.Minute {
+		t.Errorf("AddDuration failure: got %v", v)
+	}
+	if v, _ := cr.Time("s", "time"); !v.Equal(now) {
+		t.Errorf("AddTime failure: got %v", v)
+	}
+	if v, _ := cr.RawString("s", "mib"); v != "512MiB" {
+		t.Errorf("AddByteSize failure: got %q", v)
+	}
+	if v, _ := cr.ByteSize("s", "mb"); v != 1500000000 {
+		t.Errorf("AddByteSize failure: got %d", v)
+	}
+	if v, _ := cr.URL("s", "url"); v == nil || v.String() != u.String() {
+		t.Errorf("AddURL failure: got %v", v)
+	}
+	if h, p, _ := cr.HostPort("s", "hostport"); h != "::1" || p != 8080 {
+		t.Errorf("AddHostPort failure: got %s %d", h, p)
+	}
+}
+
+// Tests schema validation and defaults.
+func TestSchema(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[service-1]\nurl: http://example.com\nmaxclient: 200\n" +
+		"maxclients: 2000\nlevel: loud\ntimeout: soon\n[other]\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	schema := &Schema{Sections: []*SectionSchema{
+		{Name: "service-1", Options: []*OptionSchema{
+			{Name: "url", Type: reflect.TypeOf((*url.URL)(nil)), Required: true},
+			{Name: "maxclients", Type: reflect.TypeOf(0), Range: &Range{1, 1000}},
+			{Name: "level", Enum: []string{"debug", "info"}, Default: "info"},
+			{Name: "timeout", Type: reflect.TypeOf(time.Second), Default: "30s"},
+			{Name: "delegation", Type: reflect.TypeOf(true), Default: "on"},
+			{Name: "user", Required: true},
+		}},
+		{Name: "required", Required: true},
+	}}
+
+	err = c.Validate(schema)
+	errs, ok := err.(SchemaErrors)
+	if !ok {
+		t.Fatalf("Validate failure: got %v", err)
+	}
+
+	expected := []string{
+		tmp + ":3: option maxclient in section service-1: unknown option",
+		tmp + ":4: option maxclients in section service-1: value out of range",
+		tmp + ":5: option level in section service-1: value not allowed",
+		tmp + ":6: option timeout in section service-1: wrong type",
+		tmp + ": section other: unknown section",
+		tmp + ": option user in section service-1: missing required option",
+		tmp + ": section required: missing required section",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Validate failure: got %d errors\n%v", len(errs), err)
+	}
+	for i, e := range errs {
+		if !strings.HasPrefix(e.Error(), expected[i]) {
+			t.Errorf("Validate failure: got %q, expected %q", e.Error(), expected[i])
+		}
+	}
+
+	c.ApplyDefaults(schema)
+	testGet(t, c, "service-1", "level", "loud") // already set
+	testGet(t, c, "service-1", "delegation", true)
+}
+
+// Tests the generation of sample files from a tagged struct.
+func TestWriteSample(t *testing.T) {
+	var settings struct {
+		Host    string `config:"host" default:"www.example.com" desc:"Host name."`
+		Service struct {
+			URL        *url.URL `config:"url,required" desc:"URL of the service."`
+			MaxClients int      `config:"maxclients" default:"200"`
+		} `config:"service-1" desc:"First service."`
+	}
+
+	schema, err := StructSchema(&settings)
+	if err != nil {
+		t.Fatalf("StructSchema failure: %v", err)
+	}
+	if err = schema.WriteSample(tmp, 0644, "Sample file", true); err != nil {
+		t.Fatalf("WriteSample failure: %v", err)
+	}
+	defer os.Remove(tmp)
+
+	b, _ := os.ReadFile(tmp)
+	expected := `# Sample file
+
+[DEFAULT]
+# Host name.
+# type: string
+# default: www.example.com
+host: www.example.com
+
+# First service.
+[service-1]
+# URL of the service.
+# type: *url.URL (required)
+# url: 
+
+# type: int
+# default: 200
+maxclients: 200
+
+`
+	if string(b) != expected {
+		t.Errorf("WriteSample failure: got\n%s", b)
+	}
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	if err = c.Validate(schema); err == nil ||
+		!strings.HasSuffix(err.Error(), "option url in section service-1: missing required option") {
+		t.Errorf("Validate failure: got %v", err)
+	}
+}
+
+// Tests the detection of unknown and unused options.
+func TestUnknown(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("", "host", "www.example.com")
+	c.AddOption("service-1", "url", "http://%(host)s/")
+	c.AddOption("service-1", "maxclient", "200")
+	c.AddOption("service-1", "delegation", "on")
+	c.AddOption("servce-2", "url", "http://%(host)s/")
+
+	schema := &Schema{Sections: []*SectionSchema{
+		{Name: "service-1", Options: []*OptionSchema{
+			{Name: "url"}, {Name: "maxclients"}, {Name: "delegation"},
+		}},
+		{Name: "service-2"},
+	}}
+
+	unknown := c.Unknown(schema)
+	if len(unknown) != 2 ||
+		unknown[0].String() != "option maxclient in section service-1: did you mean maxclients?" ||
+		unknown[1].String() != "section servce-2: did you mean service-2?" {
+		t.Errorf("Unknown failure: got %v", unknown)
+	}
+
+	err := c.Validate(schema)
+	if err == nil || !strings.Contains(err.Error(), "unknown option: did you mean maxclients?") {
+		t.Errorf("Validate failure: got %v", err)
+	}
+
+	c.TrackAccess(true)
+	c.String("service-1", "url")
+	c.Int("service-1", "maxclients")
+	if !c.Accessed(_DEFAULT_SECTION, "host") || c.Accessed("service-1", "delegation") {
+		t.Errorf("Accessed failure")
+	}
+
+	unused := c.Unused()
+	if len(unused) != 3 || unused[0].Option != "maxclient" ||
+		len(unused[0].Suggestions) != 1 || unused[1].Option != "delegation" ||
+		unused[2].Section != "servce-2" {
+		t.Errorf("Unused failure: got %v", unused)
+	}
+}
+
+// Tests deprecated names of sections and options.
+func TestAliases(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("service", "max-clients", "200")
+	c.AddOption("service", "url", "http://www.example.com")
+	c.AddOption("service", "timeout", "10s")
+	c.AddOption("old-db", "host", "localhost")
+
+	c.AddAlias("service", "max-clients", "service", "maxclients")
+	c.AddAlias("service", "url", "client", "url")
+	c.AddAlias("old-db", "", "database", "")
+
+	var warnings []Alias
+	c.SetDeprecationHook(func(a Alias) { warnings = append(warnings, a) })
+
+	testGet(t, c, "service", "maxclients", 200)
+	testGet(t, c, "service", "maxclients", 200)
+	testGet(t, c, "database", "host", "localhost")
+	if !c.HasOption("client", "url") {
+		t.Errorf("HasOption failure: alias not resolved")
+	}
+	if len(warnings) != 3 || warnings[0].OldOption != "max-clients" {
+		t.Errorf("DeprecationHook failure: got %v", warnings)
+	}
+
+	var s struct {
+		MaxClients int `config:"maxclients"`
+	}
+	if err := c.DecodeSection("service", &s); err != nil || s.MaxClients != 200 {
+		t.Errorf("DecodeSection failure: got %v, %v", s, err)
+	}
+
+	if applied := c.MigrateAliases(); len(applied) != 3 {
+		t.Errorf("MigrateAliases failure: got %v", applied)
+	}
+	options := c.optionNames("service")
+	if strings.Join(options, ",") != "maxclients,timeout" {
+		t.Errorf("MigrateAliases failure: got options %v", options)
+	}
+	if strings.Join(c.Sections(), ",") != "DEFAULT,service,database,client" {
+		t.Errorf("MigrateAliases failure: got sections %v", c.Sections())
+	}
+	if _, ok := c.data["client"]["url"]; !ok {
+		t.Errorf("MigrateAliases failure: option not moved")
+	}
+}
+
+// Tests versioned migrations of a configuration file.
+func TestMigrations(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[DEFAULT]\nhost: example.com\n[service]\nmax-clients: 200\n" +
+		"timeout: 10\nlegacy: yes\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	m := NewMigrations("")
+	m.Add(1,
+		RenameOptionStep("service", "max-clients", "maxclients"),
+		DeleteOptionStep("service", "legacy"))
+	m.Add(2,
+		TransformValueStep("service", "timeout", func(v string) (string, error) {
+			return v + "s", nil
+		}),
+		MoveOptionStep("", "host", "service", "host"),
+		RenameSectionStep("service", "service-1"))
+
+	report, err := m.UpgradeFile(tmp, 0644, "")
+	if err != nil || report.From != 0 || report.To != 2 || len(report.Changes) != 5 {
+		t.Fatalf("UpgradeFile failure: got %+v, %v", report, err)
+	}
+
+	c, report, err := m.Read(tmp)
+	if err != nil || report.Changed() {
+		t.Fatalf("Read failure: got %+v, %v", report, err)
+	}
+	if strings.Join(c.optionNames("service-1"), ",") != "maxclients,timeout,host" {
+		t.Errorf("Migrate failure: got options %v", c.optionNames("service-1"))
+	}
+	if d, _ := c.Duration("service-1", "timeout"); d != 10*time.Second {
+		t.Errorf("Migrate failure: got timeout %v", d)
+	}
+	if v, _ := m.Version(c); v != 2 {
+		t.Errorf("Version failure: got %d", v)
+	}
+
+	m = NewMigrations("")
+	m.Add(1)
+	if _, err = m.Migrate(c); err == nil {
+		t.Errorf("Migrate failure: no error for newer version")
+	}
+}
+
+// Tests git-config style subsections.
+func TestSubsections(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[remote \"origin\"]\nurl: https://example.com/a\n" +
+		"[remote   \"with \\\"quotes\\\" \\\\ and spaces\"]\nurl: https://example.com/b\n" +
+		"[branch \"main\"]\nremote: origin\n[My Section]\nfoo: bar\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	subs := c.Subsections("remote")
+	if len(subs) != 2 || subs[0] != "origin" || subs[1] != `with "quotes" \ and spaces` {
+		t.Errorf("Subsections failure: got %q", subs)
+	}
+	testGet(t, c, Subsection("remote", subs[1]), "url", "https://example.com/b")
+	testGet(t, c, "My Section", "foo", "bar")
+	if !c.HasSubsection("branch", "main") || c.HasSubsection("branch", "origin") {
+		t.Errorf("HasSubsection failure")
+	}
+
+	c.WriteFile(tmp, 0644, "")
+	cr, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	if subs = cr.Subsections("remote"); len(subs) != 2 || subs[1] != `with "quotes" \ and spaces` {
+		t.Errorf("Subsections failure after write: got %q", subs)
+	}
+
+	if err = c.read(bufio.NewReader(strings.NewReader("[remote \"origin]\n"))); err == nil {
+		t.Errorf("read failure: no error for unterminated subsection")
+	}
+}
+
+// Tests the hierarchical view over dotted section names.
+func TestHierarchy(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("", "host", "example.com")
+	c.AddOption("server", "timeout", "30s")
+	c.AddOption("server.http", "port", "80")
+	c.AddOption("server.http.tls", "port", "443")
+	c.AddOption("server.grpc", "port", "9090")
+	c.AddOption("server.grpc", "timeout", "5s")
+	c.AddOption("client", "url", "http://%(host)s")
+
+	if children := c.Children(""); strings.Join(children, ",") != "server,client" {
+		t.Errorf("Children failure: got %v", children)
+	}
+	if children := c.Children("server"); strings.Join(children, ",") != "http,grpc" {
+		t.Errorf("Children failure: got %v", children)
+	}
+
+	if c.HasOption("server.http.tls", "timeout") {
+		t.Errorf("HasOption failure: option inherited without hierarchy")
+	}
+	c.SetHierarchical(true)
+	if d, err := c.Duration("server.http.tls", "timeout"); err != nil || d != 30*time.Second {
+		t.Errorf("Duration failure: got %v, %v", d, err)
+	}
+	if d, _ := c.Duration("server.grpc", "timeout"); d != 5*time.Second {
+		t.Errorf("Duration failure: got %v", d)
+	}
+	if opts, _ := c.Options("server.http.tls"); len(opts) != 3 {
+		t.Errorf("Options failure: got %v", opts)
+	}
+
+	sub := c.SubConfig("server")
+	if s := sub.Sections(); strings.Join(s, ",") != "DEFAULT,http,http.tls,grpc" {
+		t.Errorf("SubConfig failure: got sections %v", s)
+	}
+	testGet(t, sub, _DEFAULT_SECTION, "timeout", "30s")
+	testGet(t, sub, "http.tls", "port", 443)
+	testGet(t, c, "server.http", "port", 80) // unchanged
+}
+
+// Tests section inheritance.
+func TestInheritance(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[DEFAULT]\nuser: admin\n[base]\nhost: db.example.com\nport: 5432\n" +
+		"url: %(host)s:%(port)s\n[common]\nport: 6543\ntimeout: 10s\n" +
+		"[prod : base, common]\nhost: prod.example.com\n" +
+		"[staging]\ninherits: prod\nport: 7654\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	testGet(t, c, "prod", "port", 5432) // first parent wins
+	testGet(t, c, "prod", "timeout", "10s")
+	testGet(t, c, "prod", "url", "prod.example.com:5432")
+	testGet(t, c, "staging", "url", "prod.example.com:7654")
+	if !c.HasOption("staging", "timeout") {
+		t.Errorf("HasOption failure: inherited option not found")
+	}
+	if opts, _ := c.Options("prod"); len(opts) != 5 {
+		t.Errorf("Options failure: got %v", opts)
+	}
+
+	if err = c.SetParents("base", "staging"); err == nil ||
+		err.Error() != "inheritance cycle: base -> staging -> prod -> base" {
+		t.Errorf("SetParents failure: got %v", err)
+	}
+	if len(c.Parents("base")) != 0 {
+		t.Errorf("SetParents failure: parents set despite cycle")
+	}
+
+	c.WriteFile(tmp, 0644, "")
+	b, _ := os.ReadFile(tmp)
+	if !strings.Contains(string(b), "\n[prod : base, common]\n") {
+		t.Errorf("WriteFile failure: got\n%s", b)
+	}
+
+	_, err = Read(tmp, DEFAULT_COMMENT, DEFAULT_SEPARATOR, false, true)
+	if err != nil {
+		t.Errorf("Read failure: %v", err)
+	}
+	os.WriteFile(tmp, []byte("[a : b]\n[b]\ninherits: a\n"), 0644)
+	if _, err = ReadDefault(tmp); err == nil {
+		t.Errorf("ReadDefault failure: no error for inheritance cycle")
+	}
+
+	// Colons without spaces around them are part of the name.
+	c = NewDefault()
+	c.AddOption("host:8080", "a", "1")
+	c.AddOption("http://example.com", "b", "2")
+	c.AddOption("c", "d", "3")
+	c.SetParents("c", "host:8080")
+	if err = c.WriteFile(tmp, 0644, ""); err != nil {
+		t.Fatalf("WriteFile failure: %v", err)
+	}
+	if c, err = ReadDefault(tmp); err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	if s := c.Sections(); strings.Join(s, ",") != "DEFAULT,host:8080,http://example.com,c" {
+		t.Errorf("ReadDefault failure: got sections %v", s)
+	}
+	testGet(t, c, "c", "a", "1")
+
+	// Subsections inherit, and are inherited from, too.
+	c = NewDefault()
+	c.AddOption(Subsection("remote", "base, : x"), "url", "https://example.com")
+	c.AddOption("base", "push", "no")
+	c.SetParents(Subsection("remote", "origin"), "base", Subsection("remote", "base, : x"))
+	if err = c.WriteFile(tmp, 0644, ""); err != nil {
+		t.Fatalf("WriteFile failure: %v", err)
+	}
+	if c, err = ReadDefault(tmp); err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+	testGet(t, c, Subsection("remote", "origin"), "url", "https://example.com")
+	testGet(t, c, Subsection("remote", "origin"), "push", "no")
+
+	os.WriteFile(tmp, []byte("[kept]\n"), 0644)
+	for _, section := range []string{"a : b", " a", "a, b"} {
+		c = NewDefault()
+		if strings.Contains(section, ",") {
+			c.SetParents("c", section)
+		} else {
+			c.AddSection(section)
+		}
+		if err = c.WriteFile(tmp, 0644, ""); err == nil {
+			t.Errorf("WriteFile failure: no error for section %q", section)
+		}
+	}
+	if b, _ := os.ReadFile(tmp); string(b) != "[kept]\n" {
+		t.Errorf("WriteFile failure: file changed despite error, got %q", b)
+	}
+}
+
+// Tests profiles and their activation.
+func TestProfiles(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[DEFAULT]\nscheme: http\nscheme@prod: https\n" +
+		"[database]\nhost: localhost\nport: 5432\nhost@prod: db.example.com\n" +
+		"url: %(scheme)s://%(host)s:%(port)s\n" +
+		"[database@staging]\nhost: staging.example.com\nport: 6543\n" +
+		"[database@prod]\nhost: ignored.example.com\nport: 7654\n" +
+		"[cache@prod]\nsize: 1GB\n" +
+		"[users]\nbob@example.com: admin\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	if p := c.Profiles(); strings.Join(p, ",") != "prod,staging" {
+		t.Errorf("Profiles failure: got %v", p)
+	}
+
+	dev := c.Activate("dev")
+	testGet(t, dev, "database", "url", "http://localhost:5432")
+	if dev.HasSection("cache") || len(dev.Sections()) != 3 {
+		t.Errorf("Activate failure: got sections %v", dev.Sections())
+	}
+
+	staging := c.Activate("staging")
+	testGet(t, staging, "database", "url", "http://staging.example.com:6543")
+
+	prod := c.Activate("prod")
+	testGet(t, prod, "database", "url", "https://db.example.com:7654")
+	if n, _ := prod.ByteSize("cache", "size"); n != 1e9 {
+		t.Errorf("Activate failure: got cache size %d", n)
+	}
+	testGet(t, prod, "users", "bob@example.com", "admin")
+	if strings.Join(prod.optionNames("database"), ",") != "host,port,url" {
+		t.Errorf("Activate failure: got options %v", prod.optionNames("database"))
+	}
+
+	// The copy has its own settings and access tracking.
+	c.TrackAccess(true)
+	c.RegisterConverter(reflect.TypeOf(testLevel(0)), func(string) (interface{}, error) {
+		return testLevel(1), nil
+	})
+	c.AddAlias("db", "", "database", "")
+	warnings := 0
+	c.SetDeprecationHook(func(Alias) { warnings++ })
+	dev = c.Activate("dev")
+	dev.String("database", "url")
+	dev.RegisterConverter(reflect.TypeOf(testLevel(0)), nil)
+	dev.SetBoolStrings(StrictBoolStrings)
+	dev.AddOption("db", "user", "admin")
+	dev.String("database", "user")
+	if c.Accessed("database", "url") || !dev.Accessed("database", "url") {
+		t.Errorf("Activate failure: access tracking shared")
+	}
+	if _, ok := c.converters[reflect.TypeOf(testLevel(0))]; !ok || c.boolString["t"] != true {
+		t.Errorf("Activate failure: settings shared")
+	}
+	c.AddOption("db", "user", "admin")
+	c.String("database", "user")
+	if warnings != 2 {
+		t.Errorf("Activate failure: got %d deprecation warnings", warnings)
+	}
+}
+
+// Tests paths and queries.
+func TestQuery(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("service-1", "url", "http://%(host)s:%(port)s/")
+	c.AddOption("service-1", "host", "www.example.com")
+	c.AddOption("service-1", "port", "8080")
+	c.AddOption("server.http", "port", "80")
+	c.AddOption("server.http", "log.level", "debug")
+	c.AddOption(Subsection("remote", "origin"), "url", "https://example.com/a.git")
+	c.AddOption(Subsection("remote", "my.fork"), "url", "https://example.com/b.git")
+
+	queries := map[string]string{
+		"service-1.url":          "http://www.example.com:8080/",
+		"server.http.port":       "80",
+		`server.http.log\.level`: "debug",
+		`remote "origin".url`:    "https://example.com/a.git",
+		`remote "my.fork".url`:   "https://example.com/b.git",
+	}
+	for p, expected := range queries {
+		if v, err := c.Query(p); err != nil || v != expected {
+			t.Errorf("Query(%q) failure: got %q, %v", p, v, err)
+		}
+	}
+
+	for _, p := range []string{"service-1", ".url", "service-1.", `remote "origin.url`, "*.port", `s.a\`} {
+		if _, err := c.Query(p); err == nil {
+			t.Errorf("Query(%q) failure: expected error", p)
+		}
+	}
+
+	for _, tc := range []struct{ section, option string }{
+		{"server.http", "log.level"},
+		{Subsection("remote", `my "fork".*`), "url"},
+		{"a*b", `c\d`},
+		{"s", `a\`},
+		{`s\`, `\\`},
+	} {
+		section, option, err := SplitPath(Path(tc.section, tc.option))
+		if err != nil || section != tc.section || option != tc.option {
+			t.Errorf("Path(%q, %q) failure: got %q, %q, %v", tc.section, tc.option, section, option, err)
+		}
+	}
+
+	c.AddOption("api/v1", "port", "8081")
+	matches, err := c.QueryAll("*.port")
+	if err != nil || len(matches) != 3 || matches[1] != (Match{"server.http", "port", "80"}) ||
+		matches[2].Section != "api/v1" {
+		t.Errorf("QueryAll failure: got %v, %v", matches, err)
+	}
+	matches, err = c.QueryAll(`remote "*".url`)
+	if err != nil || len(matches) != 2 || matches[0].Section != Subsection("remote", "origin") {
+		t.Errorf("QueryAll failure: got %v, %v", matches, err)
+	}
+	if matches, _ = c.QueryAll("*.url"); len(matches) != 1 {
+		t.Errorf("QueryAll failure: subsections matched by %v", matches)
+	}
+	if matches, _ = c.QueryAll(`server.h?tp.[pl]*`); len(matches) != 0 {
+		t.Errorf("QueryAll failure: brackets taken as a class, got %v", matches)
+	}
+	if matches, _ = c.QueryAll(`se*.h?tp.*`); len(matches) != 2 {
+		t.Errorf("QueryAll failure: got %v", matches)
+	}
+}
+
+// Tests ordered iteration over sections and options.
+func TestIterators(t *testing.T) {
+	file, _ := os.Create(tmp)
+	file.WriteString("[DEFAULT]\nhost: localhost\nport: 80\n" +
+		"[web]\nport: 8080\nurl: http://%(host)s:%(port)s/\n" +
+		"[api : web]\npath: /api\n" +
+		"[cache]\nsize: 1GB\n")
+	file.Close()
+	defer os.Remove(tmp)
+
+	c, err := ReadDefault(tmp)
+	if err != nil {
+		t.Fatalf("ReadDefault failure: %v", err)
+	}
+
+	var sections []string
+	for section := range c.AllSections() {
+		sections = append(sections, section)
+	}
+	if strings.Join(sections, ",") != "DEFAULT,web,api,cache" {
+		t.Errorf("AllSections failure: got %v", sections)
+	}
+
+	var got []string
+	for option, e := range c.AllOptions("api") {
+		value, err := e.Value()
+		if err != nil {
+			t.Fatalf("Value failure: %v", err)
+		}
+		got = append(got, option+"="+value+"@"+e.Holder+"/"+strconv.FormatBool(e.Default))
+	}
+	expected := "path=/api@api/false port=8080@web/false url=http://localhost:8080/@web/false " +
+		"host=localhost@DEFAULT/true"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("AllOptions failure: got %v", got)
+	}
+
+	for option, e := range c.AllOptions("web") {
+		if option == "url" && (e.Raw[0] != "http://%(host)s:%(port)s/" || e.Inherited()) {
+			t.Errorf("AllOptions failure: got %+v", e)
+		}
+		if option == "port" {
+			break
+		}
+	}
+
+	if opts, _ := c.Options("cache"); strings.Join(opts, ",") != "size,host,port" {
+		t.Errorf("Options failure: got %v", opts)
+	}
+	for range c.AllOptions("no-section") {
+		t.Errorf("AllOptions failure: unknown section iterated")
+	}
+}
+
+// Tests that the input order is kept across removals and writes.
+func TestOrder(t *testing.T) {
+	c := NewDefault()
+	for _, section := range []string{"a", "b", "c"} {
+		for _, option := range []string{"x", "y", "z"} {
+			c.AddOption(section, option, section+option)
+		}
+	}
+	c.RemoveSection("b")
+	c.RemoveOption("a", "y")
+	c.AddOption("a", "x", "overwritten")
+	c.AddOption("a", "w", "aw")
+	c.AddSection("b")
+
+	if s := c.Sections(); strings.Join(s, ",") != "DEFAULT,a,c,b" {
+		t.Errorf("Sections failure: got %v", s)
+	}
+	if o := c.optionNames("a"); strings.Join(o, ",") != "x,z,w" {
+		t.Errorf("Options failure: got %v", o)
+	}
+
+	for i := 0; i < 2; i++ { // writing leaves the configuration unchanged
+		if err := c.WriteFile(tmp, 0644, ""); err != nil {
+			t.Fatalf("WriteFile failure: %v", err)
+		}
+	}
+	defer os.Remove(tmp)
+
+	b, _ := os.ReadFile(tmp)
+	expected := "\n[a]\nx: overwritten\nz: az\nw: aw\n\n[c]\nx: cx\ny: cy\nz: cz\n\n[b]\n\n"
+	if string(b) != expected {
+		t.Errorf("WriteFile failure: got %q", b)
+	}
+}
+
+// Tests that unfolded values follow the changes to the configuration.
+func TestUnfoldCache(t *testing.T) {
+	c := NewDefault()
+	c.AddOption(_DEFAULT_SECTION, "hosts", "a,b")
+	c.AddOption("web", "host", "localhost")
+	c.AddOption("web", "url", "http://%(host)s/?%(hosts)s")
+	c.AddOption("api", "url", "%(host)s/api")
+	c.AddOption("base", "host", "base.example.com")
+
+	testGet(t, c, "web", "url", "http://localhost/?a,b")
+	c.AddOption("web", "host", "www.example.com")
+	testGet(t, c, "web", "url", "http://www.example.com/?a,b")
+	c.AppendValue(_DEFAULT_SECTION, "hosts", "c")
+	testGet(t, c, "web", "url", "http://www.example.com/?c")
+	c.RemoveOption("web", "host")
+	if _, err := c.String("web", "url"); err == nil {
+		t.Errorf("String failure: removed variable unfolded")
+	}
+
+	if _, err := c.String("api", "url"); err == nil {
+		t.Errorf("String failure: unknown variable unfolded")
+	}
+	c.SetParents("api", "base")
+	testGet(t, c, "api", "url", "base.example.com/api")
+	c.SetParents("api")
+	c.AddOption("api.v2", "url", "%(host)s/api/v2")
+	c.AddOption("api", "host", "api.example.com")
+	c.SetHierarchical(true)
+	testGet(t, c, "api.v2", "url", "api.example.com/api/v2")
+	c.SetHierarchical(false)
+	if _, err := c.String("api.v2", "url"); err == nil {
+		t.Errorf("String failure: variable inherited without hierarchy")
+	}
+
+	c.AddOption("c", "inherits", "b; api")
+	c.AddOption("c", "path", "%(host)s/c")
+	if _, err := c.String("c", "path"); err == nil {
+		t.Errorf("String failure: variable inherited from unknown section")
+	}
+	c.SetListSeparator(";")
+	testGet(t, c, "c", "path", "api.example.com/c")
+}
+
+// === Benchmarks
+// ===
+
+const benchSections, benchOptions = 100, 100 // 10,000 options
+
+// benchConfig returns a configuration with benchSections sections of
+// benchOptions options each, the last one of every section interpolating
+// the previous ones.
+func benchConfig() *Config {
+	c := NewDefault()
+	c.AddOption(_DEFAULT_SECTION, "root", "/srv")
+	for i := 0; i < benchSections; i++ {
+		section := "section-" + strconv.Itoa(i)
+		for j := 0; j < benchOptions-1; j++ {
+			c.AddOption(section, "option-"+strconv.Itoa(j), "value-"+strconv.Itoa(j))
+		}
+		c.AddOption(section, "path", "%(root)s/%(option-0)s/%(option-1)s/%(option-2)s")
+	}
+	return c
+}
+
+func BenchmarkRead(b *testing.B) {
+	if err := benchConfig().WriteFile(tmp, 0644, ""); err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(tmp)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadDefault(tmp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRemove removes every option, then every section.
+func BenchmarkRemove(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c := benchConfig()
+		b.StartTimer()
+
+		for _, section := range c.Sections() {
+			for _, option := range c.optionNames(section) {
+				c.RemoveOption(section, option)
+			}
+			c.RemoveSection(section)
+		}
+	}
+}
+
+// BenchmarkRename renames every option, then every section.
+func BenchmarkRename(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c := benchConfig()
+		b.StartTimer()
+
+		for _, section := range c.Sections() {
+			for _, option := range c.optionNames(section) {
+				if err := c.RenameOption(section, option, option+"-renamed"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if section != _DEFAULT_SECTION {
+				if err := c.RenameSection(section, section+"-renamed"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	c := benchConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		section := "section-" + strconv.Itoa(i%benchSections)
+		if _, err := c.String(section, "option-"+strconv.Itoa(i%(benchOptions-1))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpolation(b *testing.B) {
+	c := benchConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.String("section-"+strconv.Itoa(i%benchSections), "path"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInterpolationUncached unfolds without the cache of unfolded
+// values, for comparison with BenchmarkInterpolation.
+func BenchmarkInterpolationUncached(b *testing.B) {
+	c := benchConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.changed()
+		if _, err := c.String("section-"+strconv.Itoa(i%benchSections), "path"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	c := benchConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := bufio.NewWriter(io.Discard)
+		if err := c.write(buf, ""); err != nil {
+			b.Fatal(err)
+		}
+		buf.Flush()
+	}
+}
+
+// Tests renaming, moving and copying sections and options.
+func TestRename(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("a", "x", "1")
+	c.AddOption("a", "y", "2")
+	c.AddOption("a", "z", "%(y)s")
+	c.SetComment("a", "", "section a")
+	c.SetComment("a", "y", "option y")
+	c.AddOption("b", "w", "3")
+	c.SetParents("c", "a")
+
+	if err := c.RenameSection("a", "b"); err == nil {
+		t.Errorf("RenameSection failure: existing section overwritten")
+	}
+	if err := c.RenameSection("a", "d"); err != nil {
+		t.Fatalf("RenameSection failure: %v", err)
+	}
+	if s := c.Sections(); strings.Join(s, ",") != "DEFAULT,d,b,c" {
+		t.Errorf("RenameSection failure: got sections %v", s)
+	}
+	if c.Comment("d", "") != "section a" || c.Parents("c")[0] != "d" {
+		t.Errorf("RenameSection failure: comment or inheritance lost")
+	}
+	testGet(t, c, "c", "z", "2")
+
+	c.AddOption("g", "inherits", "b, d")
+	if err := c.RenameSection("d", "a"); err != nil {
+		t.Fatalf("RenameSection failure: %v", err)
+	}
+	testGet(t, c, "g", "inherits", "b, a")
+	testGet(t, c, "g", "z", "2")
+	c.RenameSection("a", "d")
+	c.RemoveSection("g")
+
+	if err := c.RenameOption("d", "y", "v"); err != nil {
+		t.Fatalf("RenameOption failure: %v", err)
+	}
+	if o := c.optionNames("d"); strings.Join(o, ",") != "x,v,z" || c.Comment("d", "v") != "option y" {
+		t.Errorf("RenameOption failure: got %v", o)
+	}
+	if err := c.RenameOption("d", "x", "z"); err == nil {
+		t.Errorf("RenameOption failure: existing option overwritten")
+	}
+
+	if err := c.MoveOption("d", "x", "b", "x"); err != nil {
+		t.Fatalf("MoveOption failure: %v", err)
+	}
+	if err := c.CopyOption("d", "v", "b", "y"); err != nil {
+		t.Fatalf("CopyOption failure: %v", err)
+	}
+	if o := c.optionNames("b"); strings.Join(o, ",") != "w,x,y" || c.Comment("b", "y") != "option y" {
+		t.Errorf("MoveOption/CopyOption failure: got %v", o)
+	}
+	if c.HasOption("d", "x") || !c.HasOption("d", "v") {
+		t.Errorf("MoveOption/CopyOption failure: source not moved or copied")
+	}
+
+	c.SetMultiValue(true)
+	c.AddOption("b", "m", "1")
+	c.AddOption("b", "m", "2")
+	if err := c.CopySection("b", "e"); err != nil {
+		t.Fatalf("CopySection failure: %v", err)
+	}
+	c.AddOption("e", "m", "3")
+	if v, _ := c.RawValues("b", "m"); len(v) != 2 {
+		t.Errorf("CopySection failure: values shared, got %v", v)
+	}
+	if o := c.optionNames("e"); strings.Join(o, ",") != "w,x,y,m" {
+		t.Errorf("CopySection failure: got %v", o)
+	}
+
+	for _, err := range []error{
+		c.RenameSection(_DEFAULT_SECTION, "f"),
+		c.RenameSection("no-section", "f"),
+		c.MoveOption("d", "no-option", "b", "n"),
+		c.CopySection("no-section", "f"),
+	} {
+		if err == nil {
+			t.Errorf("rename failure: expected error")
+		}
+	}
+}
+
+// Tests ordering control over sections and options.
+func TestOrdering(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("b", "y", "2")
+	c.AddOption("b", "x", "1")
+	c.AddOption("d", "z", "3")
+
+	if err := c.AddSectionBefore("a", "b"); err != nil {
+		t.Fatalf("AddSectionBefore failure: %v", err)
+	}
+	if err := c.AddSectionAfter("c", "b"); err != nil {
+		t.Fatalf("AddSectionAfter failure: %v", err)
+	}
+	if err := c.AddSectionAfter("d", "a"); err != nil { // existing section
+		t.Fatalf("AddSectionAfter failure: %v", err)
+	}
+	if s := c.Sections(); strings.Join(s, ",") != "DEFAULT,a,d,b,c" {
+		t.Errorf("AddSection failure: got %v", s)
+	}
+	if err := c.AddSectionBefore("e", "no-section"); err == nil || c.HasSection("e") {
+		t.Errorf("AddSectionBefore failure: unknown section accepted")
+	}
+
+	if err := c.ReorderSections("c", "b", "c"); err != nil {
+		t.Fatalf("ReorderSections failure: %v", err)
+	}
+	var sections []string
+	for section := range c.AllSections() {
+		sections = append(sections, section)
+	}
+	if strings.Join(sections, ",") != "c,b,DEFAULT,a,d" {
+		t.Errorf("ReorderSections failure: got %v", sections)
+	}
+	if err := c.ReorderSections("a", "no-section"); err == nil {
+		t.Errorf("ReorderSections failure: unknown section accepted")
+	}
+
+	c.AddOptionBefore("b", "w", "0", "y")
+	c.AddOptionAfter("b", "v", "4", "y")
+	if o := c.optionNames("b"); strings.Join(o, ",") != "w,y,v,x" {
+		t.Errorf("AddOption failure: got %v", o)
+	}
+	if err := c.AddOptionAfter("b", "u", "5", "no-option"); err == nil || c.HasOption("b", "u") {
+		t.Errorf("AddOptionAfter failure: unknown option accepted")
+	}
+
+	c.SortOptions("b", nil)
+	if o := c.optionNames("b"); strings.Join(o, ",") != "v,w,x,y" {
+		t.Errorf("SortOptions failure: got %v", o)
+	}
+	c.SortOptions("b", func(a, b string) bool { return a > b })
+
+	if err := c.WriteFile(tmp, 0644, ""); err != nil {
+		t.Fatalf("WriteFile failure: %v", err)
+	}
+	defer os.Remove(tmp)
+
+	b, _ := os.ReadFile(tmp)
+	expected := "\n[c]\n\n[b]\ny: 2\nx: 1\nw: 0\nv: 4\n\n[a]\n\n[d]\nz: 3\n\n"
+	if string(b) != expected {
+		t.Errorf("WriteFile failure: got %q", b)
+	}
+}
+
+// Tests concurrent getters; run with the race detector.
+func TestConcurrentGetters(t *testing.T) {
+	c := NewDefault()
+	c.AddOption("old", "host", "example.com")
+	c.AddOption("new", "url", "http://%(host)s/")
+	c.AddOption("new", "port", "eighty")
+	c.AddAlias("old", "", "new", "")
+
+	var warnings int32
+	var mu sync.Mutex
+	c.SetDeprecationHook(func(Alias) {
+		mu.Lock()
+		warnings++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v, err := c.String("new", "url"); err != nil || v != "http://example.com/" {
+					t.Errorf("String failure: got %q, %v", v, err)
+				}
+				c.IntDefault("new", "port", 80)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if warnings != 1 || len(c.Diagnostics()) != 800 {
+		t.Errorf("concurrent getters failure: %d warnings, %d diagnostics", warnings, len(c.Diagnostics()))
+	}
+}
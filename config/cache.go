@@ -0,0 +1,65 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "sync"
+
+// Unfolded values are cached, so that getting a value which holds variables
+// costs a map lookup once it has been unfolded. Every change to the options,
+// their inheritance or their aliases clears the cache. The cache is bypassed
+// while access tracking is enabled, so that the variables used are recorded.
+//
+// Getters, including those with a default value, can be called concurrently
+// as long as the configuration is not changed at the same time and access
+// tracking is disabled.
+
+// unfoldCache holds the unfolded values, by section and raw value.
+type unfoldCache struct {
+	mu     sync.RWMutex
+	values map[unfoldKey]string
+}
+
+type unfoldKey struct {
+	section string
+	value   string
+}
+
+func newUnfoldCache() *unfoldCache {
+	return &unfoldCache{values: make(map[unfoldKey]string)}
+}
+
+func (self *unfoldCache) get(section string, value string) (string, bool) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	v, ok := self.values[unfoldKey{section, value}]
+	return v, ok
+}
+
+func (self *unfoldCache) put(section string, value string, unfolded string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.values[unfoldKey{section, value}] = unfolded
+}
+
+func (self *unfoldCache) clear() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if len(self.values) != 0 {
+		self.values = make(map[unfoldKey]string)
+	}
+}
+
+// changed clears the values cached, after a change which can alter them.
+func (self *Config) changed() {
+	self.unfolded.clear()
+}
@@ -10,8 +10,10 @@
 package config
 
 import (
+	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 
@@ -27,21 +29,26 @@ const (
 	ALTERNATIVE_SEPARATOR = "="
 )
 
+// BoolStrings holds the strings accepted as bool values, which are compared
+// without regard to case. The first string of each list is the canonical one,
+// used to format bool values.
+type BoolStrings struct {
+	True  []string
+	False []string
+}
+
 var (
-	// Strings accepted as boolean.
-	boolString = map[string]bool{
-		"t":     true,
-		"true":  true,
-		"y":     true,
-		"yes":   true,
-		"on":    true,
-		"1":     true,
-		"f":     false,
-		"false": false,
-		"n":     false,
-		"no":    false,
-		"off":   false,
-		"0":     false,
+	// Strings accepted as boolean by default.
+	DefaultBoolStrings = BoolStrings{
+		True:  []string{"true", "t", "y", "yes", "on", "1"},
+		False: []string{"false", "f", "n", "no", "off", "0"},
+	}
+
+	// Strings accepted as boolean in strict mode, without ambiguous values
+	// such as "t" or "1".
+	StrictBoolStrings = BoolStrings{
+		True:  []string{"true", "yes", "on"},
+		False: []string{"false", "no", "off"},
 	}
 
 	varRegExp = regexp.MustCompile(`%\(([a-zA-Z0-9_.\-]+)\)s`) // %(variable)s
@@ -52,22 +59,86 @@ var (
 type Config struct {
 	comment   string
 	separator string
+	fname     string // Name of the file read, if any
+
+	// Repeated options accumulate their values instead of overwriting them.
+	multiValue bool
+
+	// Indented lines holding "=" or ":" can continue a value.
+	indentedContinuation bool
+
+	// Options are inherited from parent sections (see SetHierarchical).
+	hierarchical bool
+
+	// Separator between the items of list values.
+	listSeparator string
+
+	// Layouts accepted for time values.
+	timeLayouts []string
+
+	// Strings accepted as bool values, and canonical spelling of each value.
+	boolString          map[string]bool
+	boolTrue, boolFalse string
+
+	// Registry of default values (see SetDefaults).
+	defaults *Defaults
+
+	// Errors recorded by the getters with a default value.
+	diagnostics *diagnostics
+
+	// Converters registered for custom types.
+	converters map[reflect.Type]Converter
 
-	// === Sections order
-	lastIdSection int            // Last section identifier
-	idSection     map[string]int // Section : position
+	// Section -> option : looked up, if access tracking is enabled.
+	accessed map[string]map[string]bool
 
-	// The last option identifier used for each section.
-	lastIdOption  map[string]int // Section : last identifier
+	// Deprecated names of sections and options.
+	aliases         []*alias
+	deprecationHook DeprecationHook
+
+	// === Input order
+	sectionOrder *nameList            // Sections, in order
+	optionOrder  map[string]*nameList // Section : options, in order
+
+	sectionComment map[string]string   // Section : comment written before it
+	sectionParents map[string][]string // Section : parents declared in header
 
 	// Section -> option : value
 	data map[string]map[string]*tValue
+
+	// Values unfolded, cleared on change.
+	unfolded *unfoldCache
 }
 
-// Hold the input position for a value.
+// Hold a value and its input details.
 type tValue struct {
-	position int    // Option order
-	v        string // value
+	v       string // value
+	line    int    // Input line, 0 if not read from a file
+	comment string // Comment written before the option
+
+	// The option is written commented out.
+	disabled bool
+
+	// All values of a multi-valued option, in input order; "v" holds the
+	// last one. It is nil for options with a single value.
+	values []string
+}
+
+// all returns every value held, in input order.
+func (self *tValue) all() []string {
+	if self.values == nil {
+		return []string{self.v}
+	}
+	return self.values
+}
+
+// clone returns a copy of the value.
+func (self *tValue) clone() *tValue {
+	c := *self
+	if self.values != nil {
+		c.values = append([]string(nil), self.values...)
+	}
+	return &c
 }
 
 // New creates an empty configuration representation.
@@ -103,15 +174,100 @@ func New(comment, separator string, preSpace, postSpace bool) *Config {
 
 	c.comment = comment
 	c.separator = separator
-	c.idSection = make(map[string]int)
-	c.lastIdOption = make(map[string]int)
+	c.listSeparator = DEFAULT_LIST_SEPARATOR
+	c.timeLayouts = []string{time.RFC3339}
+	c.SetBoolStrings(DefaultBoolStrings)
+	c.sectionOrder = newNameList()
+	c.optionOrder = make(map[string]*nameList)
+	c.sectionComment = make(map[string]string)
+	c.sectionParents = make(map[string][]string)
 	c.data = make(map[string]map[string]*tValue)
+	c.unfolded = newUnfoldCache()
+	c.diagnostics = new(diagnostics)
 
 	c.AddSection(_DEFAULT_SECTION) // Default section always exists.
 
 	return c
 }
 
+// emptyCopy returns a configuration with the same settings but no options.
+// The settings are copied, so that changing those of either configuration
+// leaves the other one unchanged; access tracking starts afresh.
+func (self *Config) emptyCopy() *Config {
+	c := *self
+	c.timeLayouts = append([]string(nil), self.timeLayouts...)
+
+	c.boolString = make(map[string]bool, len(self.boolString))
+	for s, v := range self.boolString {
+		c.boolString[s] = v
+	}
+
+	c.converters = nil
+	for typ, converter := range self.converters {
+		c.RegisterConverter(typ, converter)
+	}
+
+	if self.accessed != nil {
+		c.accessed = make(map[string]map[string]bool)
+	}
+
+	c.aliases = make([]*alias, len(self.aliases))
+	for i, a := range self.aliases {
+		c.aliases[i] = &alias{Alias: a.Alias}
+	}
+
+	c.sectionOrder = newNameList()
+	c.optionOrder = make(map[string]*nameList)
+	c.sectionComment = make(map[string]string)
+	c.sectionParents = make(map[string][]string)
+	c.data = make(map[string]map[string]*tValue)
+	c.unfolded = newUnfoldCache()
+	c.diagnostics = new(diagnostics)
+
+	c.AddSection(_DEFAULT_SECTION)
+
+	return &c
+}
+
+// SetMultiValue enables or disables multi-valued options (as git-config does).
+// When enabled, AddOption appends to the values of an existing option instead
+// of overwriting them, so that repeated keys in a file are all kept.
+func (self *Config) SetMultiValue(on bool) {
+	self.multiValue = on
+}
+
+// SetIndentedContinuation enables or disables the reading of indented lines
+// holding "=" or ":" as the continuation of a value, as map values need:
+//
+//	weights: alpha=1,
+//		beta=2
+//
+// When enabled, a line right after a value and indented more than its option
+// continues the value; otherwise, such a line is read as an option, and only
+// lines without "=" or ":" continue a value. Enable it before reading the file
+// (see ReadFile).
+func (self *Config) SetIndentedContinuation(on bool) {
+	self.indentedContinuation = on
+}
+
+// SetBoolStrings sets the strings accepted as bool values, e.g.
+// StrictBoolStrings or a custom vocabulary such as "enabled" and "disabled".
+// Each list must hold at least one string.
+func (self *Config) SetBoolStrings(vocabulary BoolStrings) {
+	if len(vocabulary.True) == 0 || len(vocabulary.False) == 0 {
+		panic("bool strings not valid")
+	}
+
+	self.boolString = make(map[string]bool)
+	for _, s := range vocabulary.True {
+		self.boolString[strings.ToLower(s)] = true
+	}
+	for _, s := range vocabulary.False {
+		self.boolString[strings.ToLower(s)] = false
+	}
+	self.boolTrue, self.boolFalse = vocabulary.True[0], vocabulary.False[0]
+}
+
 // NewDefault creates a configuration representation with values by default.
 func NewDefault() *Config {
 	return New(DEFAULT_COMMENT, DEFAULT_SEPARATOR, false, true)
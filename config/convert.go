@@ -0,0 +1,297 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"encoding"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Converter converts an (unfolded) string value to a value of the type it was
+// registered for.
+type Converter func(value string) (interface{}, error)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	urlType             = reflect.TypeOf((*url.URL)(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// RegisterConverter registers the converter used by Value and Decode to get
+// values of the given type, e.g.:
+//
+//	c.RegisterConverter(reflect.TypeOf(LogLevel(0)), parseLogLevel)
+//
+// Registered converters take precedence over the built-in conversions and over
+// the encoding.TextUnmarshaler interface; nil removes the converter.
+func (self *Config) RegisterConverter(typ reflect.Type, converter Converter) {
+	if converter == nil {
+		delete(self.converters, typ)
+		return
+	}
+	if self.converters == nil {
+		self.converters = make(map[reflect.Type]Converter)
+	}
+	self.converters[typ] = converter
+}
+
+// Value gets the value for the given option in the section and stores it in
+// the value pointed to by v. The conversion used depends on the type of that
+// value, trying in turn:
+//
+//   - the converter registered for the type (see RegisterConverter);
+//   - the getter dedicated to the type: String, Float, Bool, Duration, Time,
+//     List and Map for string, float64, bool, time.Duration, time.Time,
+//     []string and map[string]string;
+//   - time.Duration, time.Time and *url.URL, as Duration, Time and URL do;
+//   - the encoding.TextUnmarshaler interface (e.g. net.IP or netip.Addr);
+//   - strings, bools, integers of every size (as Int does, so that a prefix
+//     gives the base, e.g. "0x10") and floats;
+//   - slices, whose items are got as List does;
+//   - maps with string keys, whose entries are got as Map does.
+//
+// It returns an error if either the section or the option do not exist, or
+// the value could not be converted.
+func (self *Config) Value(section string, option string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("non-nil pointer expected")
+	}
+	return self.convert(section, option, rv.Elem())
+}
+
+// convert stores the value of the option in rv, which must be settable.
+func (self *Config) convert(section string, option string, rv reflect.Value) error {
+	if _, ok := self.converters[rv.Type()]; !ok {
+		if err, ok := self.convertDedicated(section, option, rv.Addr().Interface()); ok {
+			return err
+		}
+	}
+
+	if self.isScalar(rv.Type()) {
+		sv, err := self.String(section, option)
+		if err != nil {
+			return err
+		}
+		return self.convertString(section, option, sv, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		items, err := self.List(section, option)
+		if err != nil {
+			return err
+		}
+
+		list := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err = self.convertString(section, option, item, list.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(list)
+		return nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		entries, err := self.Map(section, option)
+		if err != nil {
+			return err
+		}
+
+		m := reflect.MakeMapWithSize(rv.Type(), len(entries))
+		for k, item := range entries {
+			value := reflect.New(rv.Type().Elem()).Elem()
+			if err = self.convertString(section, option, item, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), value)
+		}
+		rv.Set(m)
+		return nil
+	}
+
+	return errors.New("unsupported type " + rv.Type().String() +
+		" for option " + option + " in section " + section)
+}
+
+// convertDedicated stores the value of the option in the value pointed to by
+// p using the getter dedicated to its type, if any. It returns false if there
+// is no such getter.
+func (self *Config) convertDedicated(section string, option string, p interface{}) (err error, ok bool) {
+	switch p := p.(type) {
+	case *string:
+		v, err := self.String(section, option)
+		return set(p, v, err), true
+	case *float64:
+		v, err := self.Float(section, option)
+		return set(p, v, err), true
+	case *bool:
+		v, err := self.Bool(section, option)
+		return set(p, v, err), true
+	case *time.Duration:
+		v, err := self.Duration(section, option)
+		return set(p, v, err), true
+	case *time.Time:
+		v, err := self.Time(section, option)
+		return set(p, v, err), true
+	case *[]string:
+		v, err := self.List(section, option)
+		return set(p, v, err), true
+	case *map[string]string:
+		v, err := self.Map(section, option)
+		return set(p, v, err), true
+	}
+	return nil, false
+}
+
+// set stores v in the value pointed to by p unless err is not nil.
+func set[T any](p *T, v T, err error) error {
+	if err == nil {
+		*p = v
+	}
+	return err
+}
+
+// isScalar reports whether values of the given type are converted from a whole
+// string value, as opposed to lists and maps.
+func (self *Config) isScalar(typ reflect.Type) bool {
+	if _, ok := self.converters[typ]; ok {
+		return true
+	}
+
+	switch typ {
+	case durationType, timeType, urlType:
+		return true
+	}
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// convertString converts sv and stores it in rv, which must be settable and
+// of a scalar type (see isScalar).
+func (self *Config) convertString(section string, option string, sv string, rv reflect.Value) error {
+	typ := rv.Type()
+	fail := func(err error) error {
+		return conversionError(section, option, sv, typ.String(), err)
+	}
+
+	if converter, ok := self.converters[typ]; ok {
+		v, err := converter(sv)
+		if err != nil {
+			return fail(err)
+		}
+
+		value := reflect.ValueOf(v)
+		if !value.IsValid() || !value.Type().AssignableTo(typ) {
+			return fail(errors.New("converter returned a value of another type"))
+		}
+		rv.Set(value)
+		return nil
+	}
+
+	switch typ {
+	case durationType:
+		d, err := time.ParseDuration(sv)
+		if err != nil {
+			return fail(nil)
+		}
+		rv.SetInt(int64(d))
+		return nil
+
+	case timeType:
+		for _, layout := range self.timeLayouts {
+			if t, err := time.Parse(layout, sv); err == nil {
+				rv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fail(nil)
+
+	case urlType:
+		u, err := url.Parse(sv)
+		if err == nil && u.Scheme == "" {
+			err = errors.New("missing scheme")
+		}
+		if err != nil {
+			if e, ok := err.(*url.Error); ok {
+				err = e.Err
+			}
+			return fail(err)
+		}
+		rv.Set(reflect.ValueOf(u))
+		return nil
+	}
+
+	if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(sv)); err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		rv.SetString(sv)
+
+	case reflect.Bool:
+		v, ok := self.boolString[strings.ToLower(sv)]
+		if !ok {
+			return fail(nil)
+		}
+		rv.SetBool(v)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := parseInteger(sv, typ.Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetInt(v)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := parseUnsigned(sv, typ.Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetUint(v)
+
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(sv, typ.Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetFloat(v)
+
+	default:
+		return fail(errors.New("unsupported type"))
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Decode stores the configuration in the struct pointed to by v. Every field
+// of struct type (and not converted as a whole value, see Value) is decoded
+// from a section, as DecodeSection does; the other fields are decoded from
+// options of the default section. For example:
+//
+//	type Settings struct {
+//		Host    string        `config:"host"`
+//		Service struct {
+//			URL        *url.URL      `config:"url"`
+//			MaxClients int           `config:"maxclients"`
+//			Timeout    time.Duration `config:"timeout"`
+//		} `config:"service-1"`
+//	}
+//
+// The name of a section or option is given by the "config" key in the field
+// tag, or is the lower-cased name of the field; the name "-" skips the field.
+// Fields whose option is not set are left unchanged.
+//
+// It returns the errors of every field which could not be decoded.
+func (self *Config) Decode(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range self.fields(rv.Type()) {
+		field := rv.FieldByIndex(f.index)
+		if f.section {
+			errs = append(errs, self.decodeSection(f.name, field))
+		} else {
+			errs = append(errs, self.decodeOption(_DEFAULT_SECTION, f.name, field))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DecodeSection stores the options of a section in the struct pointed to by v,
+// converting every one of them as Value does. Fields are named as in Decode.
+//
+// It returns the errors of every field which could not be decoded.
+func (self *Config) DecodeSection(section string, v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return self.decodeSection(section, rv)
+}
+
+func (self *Config) decodeSection(section string, rv reflect.Value) error {
+	var errs []error
+	for _, f := range self.fields(rv.Type()) {
+		errs = append(errs, self.decodeOption(section, f.name, rv.FieldByIndex(f.index)))
+	}
+	return errors.Join(errs...)
+}
+
+func (self *Config) decodeOption(section string, option string, rv reflect.Value) error {
+	if _, err := self.RawString(section, option); err != nil {
+		return nil // Not set
+	}
+	return self.convert(section, option, rv)
+}
+
+// === Utility
+// ===
+
+// structField describes a field of a struct bound to a section or an option.
+type structField struct {
+	name    string // Section or option name
+	index   []int  // Index for reflect.Value.FieldByIndex
+	section bool   // Struct decoded from a section
+	tag     reflect.StructTag
+}
+
+// fields returns the exported fields of the struct type which are not skipped.
+func (self *Config) fields(typ reflect.Type) (fields []structField) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" { // Unexported
+			continue
+		}
+
+		name := sf.Tag.Get("config")
+		if i := strings.IndexByte(name, ','); i != -1 {
+			name = name[:i]
+		}
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(sf.Name)
+		}
+
+		fields = append(fields, structField{
+			name:    name,
+			index:   sf.Index,
+			section: sf.Type.Kind() == reflect.Struct && !self.isScalar(sf.Type),
+			tag:     sf.Tag,
+		})
+	}
+	return fields
+}
+
+// required reports whether the field is tagged as required, as in
+// `config:"name,required"`.
+func (self structField) required() bool {
+	options := strings.Split(self.tag.Get("config"), ",")
+	for _, o := range options[1:] {
+		if o == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// structValue returns the struct pointed to by v.
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.New("non-nil pointer to struct expected")
+	}
+	return rv.Elem(), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults is a registry of default values, by section and option, which can
+// be installed on a configuration (see SetDefaults). Its values are used by
+// every getter when the option is not set in the configuration; they are not
+// written to files.
+type Defaults struct {
+	data map[string]map[string]string // Section -> option : value
+}
+
+// NewDefaults creates an empty registry of default values.
+func NewDefaults() *Defaults {
+	return &Defaults{make(map[string]map[string]string)}
+}
+
+// Add sets the default value of an option. If the section is empty then uses
+// the section by default.
+func (self *Defaults) Add(section string, option string, value string) {
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	if _, ok := self.data[section]; !ok {
+		self.data[section] = make(map[string]string)
+	}
+	self.data[section][option] = value
+}
+
+// get returns the default value of an option, if any.
+func (self *Defaults) get(section string, option string) (value string, ok bool) {
+	if self == nil {
+		return "", false
+	}
+	value, ok = self.data[section][option]
+	return value, ok
+}
+
+// NewWithDefaults creates a configuration representation with values by
+// default (see NewDefault) and the given registry of default values installed.
+func NewWithDefaults(defaults *Defaults) *Config {
+	c := NewDefault()
+	c.SetDefaults(defaults)
+	return c
+}
+
+// SetDefaults installs a registry of default values on the configuration;
+// nil removes it.
+func (self *Config) SetDefaults(defaults *Defaults) {
+	self.defaults = defaults
+}
+
+// Diagnostics returns the errors recorded by the getters with a default value,
+// such as StringDefault or IntDefault, for options holding malformed values.
+func (self *Config) Diagnostics() []error {
+	self.diagnostics.mu.Lock()
+	defer self.diagnostics.mu.Unlock()
+
+	return append([]error(nil), self.diagnostics.errors...)
+}
+
+// ClearDiagnostics forgets the errors recorded so far.
+func (self *Config) ClearDiagnostics() {
+	self.diagnostics.mu.Lock()
+	defer self.diagnostics.mu.Unlock()
+
+	self.diagnostics.errors = nil
+}
+
+// diagnostics holds the errors recorded by the getters with a default value,
+// which can be called concurrently.
+type diagnostics struct {
+	mu     sync.Mutex
+	errors []error
+}
+
+// === Getters with a default value
+// ===
+//
+// These getters never fail: they return the given default value if either the
+// section or the option do not exist (and the option has no value in the
+// registry of default values). A malformed value also gives the default one,
+// but its error is recorded and can be retrieved with Diagnostics.
+
+// StringDefault has the same behaviour as String but returns def on failure.
+func (self *Config) StringDefault(section string, option string, def string) string {
+	return GetDefault(self, section, option, def)
+}
+
+// IntDefault has the same behaviour as Int but returns def on failure.
+func (self *Config) IntDefault(section string, option string, def int) int {
+	return GetDefault(self, section, option, def)
+}
+
+// Int64Default has the same behaviour as Int64 but returns def on failure.
+func (self *Config) Int64Default(section string, option string, def int64) int64 {
+	return GetDefault(self, section, option, def)
+}
+
+// FloatDefault has the same behaviour as Float but returns def on failure.
+func (self *Config) FloatDefault(section string, option string, def float64) float64 {
+	return GetDefault(self, section, option, def)
+}
+
+// BoolDefault has the same behaviour as Bool but returns def on failure.
+func (self *Config) BoolDefault(section string, option string, def bool) bool {
+	return GetDefault(self, section, option, def)
+}
+
+// DurationDefault has the same behaviour as Duration but returns def on
+// failure.
+func (self *Config) DurationDefault(section string, option string, def time.Duration) time.Duration {
+	return GetDefault(self, section, option, def)
+}
+
+// ListDefault has the same behaviour as List but returns def on failure.
+func (self *Config) ListDefault(section string, option string, def []string) []string {
+	return GetDefault(self, section, option, def)
+}
+
+// fallback runs the getter if the option exists. It returns false if either
+// the option does not exist or the getter failed, recording its error.
+func (self *Config) fallback(section string, option string, getter func() error) bool {
+	if _, err := self.RawString(section, option); err != nil {
+		return false
+	}
+
+	if err := getter(); err != nil {
+		self.diagnostics.mu.Lock()
+		self.diagnostics.errors = append(self.diagnostics.errors, err)
+		self.diagnostics.mu.Unlock()
+		return false
+	}
+
+	return true
+}
@@ -9,6 +9,8 @@
 
 package config
 
+import "strconv"
+
 
 type sectionError string
 
@@ -23,3 +25,52 @@ func (self optionError) String() string {
 	return "option not found: " + string(self)
 }
 
+type sectionExistsError string
+
+func (self sectionExistsError) String() string {
+	return "section already exists: " + string(self)
+}
+
+type optionExistsError string
+
+func (self optionExistsError) String() string {
+	return "option already exists: " + string(self)
+}
+
+
+
+// ConversionError records an option whose value could not be converted to the
+// requested type.
+type ConversionError struct {
+	Section string
+	Option  string
+	Value   string
+	Type    string // Requested type, e.g. "int" or "duration"
+	Err     error  // Underlying error, if any
+}
+
+func (self *ConversionError) Error() string {
+	s := "could not parse " + self.Type + " value " + strconv.Quote(self.Value) +
+		" of option " + self.Option + " in section " + self.Section
+	if self.Err != nil {
+		s += ": " + self.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (self *ConversionError) Unwrap() error {
+	return self.Err
+}
+
+// conversionError returns a *ConversionError, taking the reason off errors from
+// the strconv package since the value is already reported.
+func conversionError(section, option, value, typ string, err error) error {
+	if e, ok := err.(*strconv.NumError); ok {
+		err = e.Err
+	}
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	return &ConversionError{section, option, value, typ, err}
+}
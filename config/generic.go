@@ -0,0 +1,43 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+// Get gets the value for the given option in the section converted to T, so
+// that a single call serves every type:
+//
+//	port, err := config.Get[int](c, "service-1", "port")
+//	timeout, err := config.Get[time.Duration](c, "service-1", "timeout")
+//	hosts, err := config.Get[[]string](c, "service-1", "hosts")
+//
+// Types with a dedicated getter (string, float64, bool, time.Duration,
+// time.Time, []string and map[string]string) are got through it; any other
+// type is converted as Value does. Struct decoding uses the same conversions.
+//
+// It returns an error if either the section or the option do not exist, or
+// the value could not be converted.
+func Get[T any](c *Config, section string, option string) (T, error) {
+	var v T
+	err := c.Value(section, option, &v)
+	return v, err
+}
+
+// GetDefault has the same behaviour as Get but never fails: it returns def if
+// either the section or the option do not exist, or if the value is malformed,
+// in which case the error is recorded as by the other getters with a default
+// value (see Diagnostics).
+func GetDefault[T any](c *Config, section string, option string, def T) T {
+	var v T
+	if c.fallback(section, option, func() error {
+		return c.Value(section, option, &v)
+	}) {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,222 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// A section can inherit the options of one or more parent sections, declared
+// in its header or with the "inherits" option:
+//
+//	[base]
+//	host: db.example.com
+//	port: 5432
+//
+//	[prod : base]
+//	host: prod.example.com
+//
+//	[staging]
+//	inherits: base
+//
+// In a header, the colon must have white space on both sides, so that section
+// names such as "host:8080" or "http://example.com" are kept whole.
+//
+// An option not set in a section is looked up in its parents, in the given
+// order and each one with its own parents, before the default section. This
+// applies to every getter, HasOption, Options and unfolding.
+
+// Option declaring the parent sections of a section, as a list.
+const INHERITS_OPTION = "inherits"
+
+// SetParents sets the parent sections of a section, which are written in its
+// header. No parents removes them. The section is created if it does not exist.
+//
+// It returns an error, and leaves the parents unchanged, if the inheritance
+// would cycle.
+func (self *Config) SetParents(section string, parents ...string) error {
+	section = sectionName(section)
+	old, had := self.sectionParents[section]
+	self.changed()
+
+	if len(parents) == 0 {
+		delete(self.sectionParents, section)
+		return nil
+	}
+	self.sectionParents[section] = append([]string(nil), parents...)
+
+	if err := self.checkInheritance(section); err != nil {
+		if had {
+			self.sectionParents[section] = old
+		} else {
+			delete(self.sectionParents, section)
+		}
+		return err
+	}
+
+	self.AddSection(section)
+	return nil
+}
+
+// Parents returns the parent sections of a section, declared either in its
+// header or with the "inherits" option.
+func (self *Config) Parents(section string) []string {
+	if parents, ok := self.sectionParents[section]; ok {
+		return append([]string(nil), parents...)
+	}
+
+	if tv, ok := self.data[section][INHERITS_OPTION]; ok {
+		parents, _ := splitList(tv.v, self.listSeparator, true)
+		return parents
+	}
+
+	return nil
+}
+
+// === Utility
+// ===
+
+// parents returns the sections an option is inherited from by the given one,
+// the nearest first, excluding the default section: the declared parents (see
+// SetParents) and, if the configuration is hierarchical, the parent nodes of
+// dotted section names (see SetHierarchical).
+func (self *Config) parents(section string) (parents []string) {
+	seen := map[string]bool{section: true, _DEFAULT_SECTION: true}
+
+	var visit func(section string)
+	visit = func(section string) {
+		next := self.Parents(section)
+		if self.hierarchical {
+			if p := parent(section); p != "" {
+				next = append(next, p)
+			}
+		}
+
+		for _, p := range next {
+			if !seen[p] {
+				seen[p] = true
+				parents = append(parents, p)
+				visit(p)
+			}
+		}
+	}
+	visit(section)
+
+	return parents
+}
+
+// checkInheritance returns an error if the declared parents of the section
+// cycle.
+func (self *Config) checkInheritance(section string) error {
+	var path []string
+	onPath := make(map[string]bool)
+	done := make(map[string]bool)
+
+	var visit func(s string) error
+	visit = func(s string) error {
+		if onPath[s] {
+			return errors.New("inheritance cycle: " +
+				strings.Join(append(path, s), " -> "))
+		}
+		if done[s] {
+			return nil
+		}
+
+		path = append(path, s)
+		onPath[s] = true
+		for _, p := range self.Parents(s) {
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[s] = false
+		done[s] = true
+
+		return nil
+	}
+
+	return visit(section)
+}
+
+// splitParents splits a section header in the section name and its parents,
+// as in "prod : base, common"; the section and the parents can hold
+// subsections, as in `remote "origin" : remote "base"`. The parents holding
+// a subsection are returned in canonical form (see Subsection).
+func splitParents(header string) (section string, parents []string) {
+	i, quoted := -1, false
+	for j := 0; j < len(header)-1 && i == -1; j++ {
+		switch {
+		case quoted && header[j] == '\\':
+			j++
+		case header[j] == '"':
+			quoted = !quoted
+		case !quoted && j > 0 && header[j] == ':' && isSpace(header[j-1]) && isSpace(header[j+1]):
+			i = j
+		}
+	}
+	if i == -1 {
+		return header, nil
+	}
+
+	items, err := splitList(header[i+1:], ",", false)
+	if err != nil {
+		return header, nil
+	}
+	for _, p := range items {
+		if name, subsection, ok, err := parseHeader(p); err == nil && ok {
+			p = Subsection(name, subsection)
+		}
+		parents = append(parents, p)
+	}
+	return strings.TrimSpace(header[:i]), parents
+}
+
+// header returns the header of a section, with its parents. It returns an
+// error if the header would not be read back as the same section and parents,
+// e.g. for a name with leading spaces or holding " : ".
+func (self *Config) header(section string) (string, error) {
+	content := section
+	parents, ok := self.sectionParents[section]
+	if ok {
+		content += " : " + strings.Join(parents, ", ")
+	}
+
+	name, p, err := parseSectionHeader(content)
+	if err != nil || name != section || len(p) != len(parents) {
+		return "", errors.New("section name not valid: " + section)
+	}
+	for i := range p {
+		if p[i] != parents[i] {
+			return "", errors.New("parent section name not valid: " + parents[i])
+		}
+	}
+
+	return "[" + content + "]", nil
+}
+
+// parseSectionHeader parses the content of a section header, between the
+// brackets, in the section name, in canonical form, and its parents.
+func parseSectionHeader(content string) (section string, parents []string, err error) {
+	header, parents := splitParents(strings.TrimSpace(content))
+	name, subsection, ok, err := parseHeader(header)
+	if err != nil {
+		return "", nil, err
+	}
+	if ok {
+		return Subsection(name, subsection), parents, nil // Canonical form
+	}
+	return name, parents, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,102 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "iter"
+
+// Entry is an option seen from a section, as yielded by AllOptions.
+type Entry struct {
+	Section string   // Section iterated
+	Option  string   // Option name
+	Holder  string   // Section holding the option: Section, a parent or the default section
+	Raw     []string // Raw values, in input order
+	Default bool     // Inherited from the default section
+
+	c *Config
+}
+
+// Inherited reports whether the option is inherited, either from a parent
+// section (see SetParents and SetHierarchical) or from the default section.
+func (self Entry) Inherited() bool {
+	return self.Holder != self.Section
+}
+
+// Value returns the last value of the option, unfolded in the iterated
+// section as String does.
+func (self Entry) Value() (string, error) {
+	return self.c.unfold(self.Section, self.Raw[len(self.Raw)-1])
+}
+
+// Values returns all the values of the option, unfolded in the iterated
+// section as Values does.
+func (self Entry) Values() (values []string, err error) {
+	values = make([]string, len(self.Raw))
+	for i, v := range self.Raw {
+		if values[i], err = self.c.unfold(self.Section, v); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// AllSections returns an iterator over the sections of the configuration, in
+// order, which is the input order unless changed (see ReorderSections).
+func (self *Config) AllSections() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, section := range self.Sections() {
+			if !yield(section) {
+				return
+			}
+		}
+	}
+}
+
+// AllOptions returns an iterator over the options available in the given
+// section, keyed by name: first the options set in the section, in input
+// order, then those inherited from its parent sections, the nearest first,
+// and last those inherited from the default section. An option is yielded
+// once, from the section it is got from. Nothing is yielded if the section
+// does not exist.
+//
+// Iterating does not record the options as accessed (see TrackAccess).
+func (self *Config) AllOptions(section string) iter.Seq2[string, Entry] {
+	return func(yield func(string, Entry) bool) {
+		if _, ok := self.data[section]; !ok {
+			return
+		}
+
+		holders := append([]string{section}, self.parents(section)...)
+		if section != _DEFAULT_SECTION {
+			holders = append(holders, _DEFAULT_SECTION)
+		}
+
+		seen := make(map[string]bool)
+		for _, holder := range holders {
+			for _, option := range self.optionNames(holder) {
+				if seen[option] {
+					continue
+				}
+				seen[option] = true
+
+				e := Entry{
+					Section: section,
+					Option:  option,
+					Holder:  holder,
+					Raw:     append([]string(nil), self.data[holder][option].all()...),
+					Default: holder == _DEFAULT_SECTION && section != _DEFAULT_SECTION,
+					c:       self,
+				}
+				if !yield(option, e) {
+					return
+				}
+			}
+		}
+	}
+}
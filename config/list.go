@@ -0,0 +1,300 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A list value holds items separated by the list separator (see
+// SetListSeparator) or by new lines, so that multi-line values can be used:
+//
+//	servers: alpha, beta,
+//		gamma
+//
+// Leading and trailing spaces are removed from every item and empty items are
+// skipped. An item can be enclosed in double quotes to hold spaces, separators,
+// quotes or comment characters; within quotes a backslash escapes the next
+// character, and "\n" and "\t" stand for a new line and a TAB.
+//
+// A map value is a list whose items are "key=value" pairs. In a file, a map
+// value stays on one line unless indented continuation lines are enabled
+// (see SetIndentedContinuation).
+
+const (
+	DEFAULT_LIST_SEPARATOR = ","
+	_MAP_SEPARATOR         = "="
+)
+
+// SetListSeparator sets the separator used between the items of list and map
+// values, both when reading them and when formatting them.
+func (self *Config) SetListSeparator(sep string) {
+	if sep == "" || strings.ContainsAny(sep, "\"\\\n") {
+		panic("list separator not valid")
+	}
+	self.listSeparator = sep
+	self.changed() // Parents declared with the inherits option
+}
+
+// List has the same behaviour as String but splits the response in a list of
+// items. For multi-valued options, the items of all values are returned.
+func (self *Config) List(section string, option string) (list []string, err error) {
+	values, err := self.Values(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		items, err := splitList(v, self.listSeparator, true)
+		if err != nil {
+			return nil, conversionError(section, option, v, "list", err)
+		}
+		list = append(list, items...)
+	}
+
+	return list, nil
+}
+
+// IntList has the same behaviour as List but converts the items to int.
+func (self *Config) IntList(section string, option string) (list []int, err error) {
+	items, err := self.List(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	list = make([]int, len(items))
+	for i, item := range items {
+		v, err := parseInteger(item, strconv.IntSize)
+		if err != nil {
+			return nil, conversionError(section, option, item, "int", err)
+		}
+		list[i] = int(v)
+	}
+
+	return list, nil
+}
+
+// FloatList has the same behaviour as List but converts the items to float.
+func (self *Config) FloatList(section string, option string) (list []float64, err error) {
+	items, err := self.List(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	list = make([]float64, len(items))
+	for i, item := range items {
+		if list[i], err = strconv.ParseFloat(item, 64); err != nil {
+			return nil, conversionError(section, option, item, "float", err)
+		}
+	}
+
+	return list, nil
+}
+
+// BoolList has the same behaviour as List but converts the items to bool.
+// See SetBoolStrings for string values converted to bool.
+func (self *Config) BoolList(section string, option string) (list []bool, err error) {
+	items, err := self.List(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	list = make([]bool, len(items))
+	for i, item := range items {
+		v, ok := self.boolString[strings.ToLower(item)]
+		if !ok {
+			return nil, conversionError(section, option, item, "bool", nil)
+		}
+		list[i] = v
+	}
+
+	return list, nil
+}
+
+// Map has the same behaviour as List but splits every item in a key and a
+// value around the first "=" sign.
+func (self *Config) Map(section string, option string) (m map[string]string, err error) {
+	values, err := self.Values(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	m = make(map[string]string)
+	for _, v := range values {
+		entries, err := splitList(v, self.listSeparator, false)
+		if err != nil {
+			return nil, conversionError(section, option, v, "map", err)
+		}
+
+		for _, entry := range entries {
+			i := indexUnquoted(entry, _MAP_SEPARATOR)
+			if i == -1 {
+				return nil, conversionError(section, option, entry, "map entry", nil)
+			}
+			m[unquoteItem(entry[:i])] = unquoteItem(entry[i+len(_MAP_SEPARATOR):])
+		}
+	}
+
+	return m, nil
+}
+
+// AddList adds a new option whose value is the given list of items, quoting
+// the items as needed so that List reads them back identically.
+// It has the same behaviour as AddOption.
+func (self *Config) AddList(section string, option string, list []string) bool {
+	return self.AddOption(section, option, self.formatList(list))
+}
+
+// AddIntList has the same behaviour as AddList but formats a list of int.
+func (self *Config) AddIntList(section string, option string, list []int) bool {
+	items := make([]string, len(list))
+	for i, v := range list {
+		items[i] = strconv.Itoa(v)
+	}
+	return self.AddList(section, option, items)
+}
+
+// AddFloatList has the same behaviour as AddList but formats a list of float.
+func (self *Config) AddFloatList(section string, option string, list []float64) bool {
+	items := make([]string, len(list))
+	for i, v := range list {
+		items[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return self.AddList(section, option, items)
+}
+
+// AddMap adds a new option whose value is the given map, with its entries
+// sorted by key, so that Map reads it back identically.
+// It has the same behaviour as AddOption.
+func (self *Config) AddMap(section string, option string, m map[string]string) bool {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		entries[i] = quoteItem(k, self.listSeparator+_MAP_SEPARATOR) +
+			_MAP_SEPARATOR + quoteItem(m[k], self.listSeparator+_MAP_SEPARATOR)
+	}
+
+	return self.AddOption(section, option, strings.Join(entries, self.listSeparator+" "))
+}
+
+// formatList joins the items, quoting those which need it.
+func (self *Config) formatList(list []string) string {
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = quoteItem(item, self.listSeparator)
+	}
+	return strings.Join(items, self.listSeparator+" ")
+}
+
+// === Utility
+// ===
+
+// splitList splits s around sep and new lines, ignoring those within quotes.
+// The items are trimmed and, if unquote is set, their quotes are removed.
+// Empty items are skipped unless they are quoted.
+func splitList(s string, sep string, unquote bool) (items []string, err error) {
+	for len(s) > 0 {
+		i := indexUnquoted(s, sep)
+		if i == -2 {
+			return nil, errors.New("unterminated quoted item")
+		}
+
+		item, next := s, ""
+		if j := strings.IndexByte(s, '\n'); j != -1 && (i == -1 || j < i) {
+			item, next = s[:j], s[j+1:]
+		} else if i != -1 {
+			item, next = s[:i], s[i+len(sep):]
+		}
+
+		if item = strings.TrimSpace(item); item != "" {
+			if unquote {
+				item = unquoteItem(item)
+			}
+			items = append(items, item)
+		}
+		s = next
+	}
+
+	return items, nil
+}
+
+// indexUnquoted returns the index of the first instance of sep in s which is
+// not within quotes, -1 if there is none, or -2 if a quote is not terminated.
+func indexUnquoted(s string, sep string) int {
+	quoted := false // within quotes
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++ // skip escaped character
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], sep):
+			return i
+		}
+	}
+	if quoted {
+		return -2
+	}
+	return -1
+}
+
+// unquoteItem trims the item and removes its enclosing quotes, if any.
+func unquoteItem(item string) string {
+	item = strings.TrimSpace(item)
+	if len(item) >= 2 && item[0] == '"' && item[len(item)-1] == '"' {
+		return unescape(item[1 : len(item)-1])
+	}
+	return item
+}
+
+// quoteItem encloses the item in quotes if it is empty, has leading or trailing
+// spaces, or contains characters which would be lost when read back.
+func quoteItem(item string, special string) string {
+	if item != "" && strings.TrimSpace(item) == item &&
+		!strings.ContainsAny(item, special+"\"\\\n\t#;") {
+		return item
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`,
+		"#", `\#`, ";", `\;`)
+	return `"` + r.Replace(item) + `"`
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b = append(b, '\n')
+				continue
+			case 't':
+				b = append(b, '\t')
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
@@ -0,0 +1,251 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Name of the option of the default section holding the format version, by
+// default.
+const DEFAULT_VERSION_OPTION = "config-version"
+
+// MigrationStep changes a configuration, returning a description of every
+// change made.
+type MigrationStep func(c *Config) (changes []string, err error)
+
+// Migrations is a registry of ordered migration steps, which upgrade a
+// configuration from the version recorded in it to the latest one. The version
+// is recorded in an option of the default section; configurations without it
+// are at version 0.
+type Migrations struct {
+	option string                  // Option holding the version
+	steps  map[int][]MigrationStep // Version : steps to reach it
+}
+
+// MigrationReport describes the changes made by Migrations.Migrate.
+type MigrationReport struct {
+	From, To int      // Versions before and after migration
+	Changes  []string // Description of every change, in order
+}
+
+// Changed reports whether the configuration was changed by the migration.
+func (self *MigrationReport) Changed() bool {
+	return self.From != self.To
+}
+
+// NewMigrations creates an empty registry of migration steps, with the version
+// held by the given option of the default section (DEFAULT_VERSION_OPTION if
+// empty).
+func NewMigrations(option string) *Migrations {
+	if option == "" {
+		option = DEFAULT_VERSION_OPTION
+	}
+	return &Migrations{option, make(map[int][]MigrationStep)}
+}
+
+// Add registers the steps which upgrade a configuration from the previous
+// version to the given one, which must be greater than 0.
+func (self *Migrations) Add(version int, steps ...MigrationStep) {
+	if version <= 0 {
+		panic("migration version not valid")
+	}
+	self.steps[version] = append(self.steps[version], steps...)
+}
+
+// Latest returns the latest version, i.e. the greatest one registered.
+func (self *Migrations) Latest() (latest int) {
+	for v := range self.steps {
+		if v > latest {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// Version returns the version recorded in the configuration.
+func (self *Migrations) Version(c *Config) (int, error) {
+	if _, ok := c.data[_DEFAULT_SECTION][self.option]; !ok {
+		return 0, nil
+	}
+	return c.Int(_DEFAULT_SECTION, self.option)
+}
+
+// Migrate applies in order the steps of every version newer than the one
+// recorded in the configuration, then records the latest version.
+//
+// It returns an error if the recorded version is newer than the latest one, or
+// if a step failed, in which case the configuration is partially migrated.
+func (self *Migrations) Migrate(c *Config) (*MigrationReport, error) {
+	from, err := self.Version(c)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := self.Latest()
+	if from > latest {
+		return nil, errors.New("configuration version " + strconv.Itoa(from) +
+			" is newer than the latest supported one, " + strconv.Itoa(latest))
+	}
+
+	report := &MigrationReport{From: from, To: from}
+	if from == latest {
+		return report, nil
+	}
+
+	versions := make([]int, 0, len(self.steps))
+	for v := range self.steps {
+		if v > from {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		for _, step := range self.steps[v] {
+			changes, err := step(c)
+			report.Changes = append(report.Changes, changes...)
+			if err != nil {
+				return report, errors.New("migration to version " + strconv.Itoa(v) +
+					" failed: " + err.Error())
+			}
+		}
+		report.To = v
+	}
+
+	c.SetValues(_DEFAULT_SECTION, self.option, []string{strconv.Itoa(latest)})
+
+	return report, nil
+}
+
+// Read reads a configuration file with values by default (see ReadDefault)
+// and migrates it to the latest version.
+func (self *Migrations) Read(fname string) (*Config, *MigrationReport, error) {
+	c, err := ReadDefault(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report, err := self.Migrate(c)
+	if err != nil {
+		return nil, report, err
+	}
+
+	return c, report, nil
+}
+
+// UpgradeFile reads a configuration file, migrates it to the latest version
+// and, if it was changed, saves it back with the given permissions and header
+// (see WriteFile). Comments in the original file are not kept.
+func (self *Migrations) UpgradeFile(fname string, perm os.FileMode, header string) (*MigrationReport, error) {
+	c, report, err := self.Read(fname)
+	if err != nil || !report.Changed() {
+		return report, err
+	}
+
+	return report, c.WriteFile(fname, perm, header)
+}
+
+// === Migration steps
+// ===
+//
+// The following steps do nothing when the option (or section) to change is
+// not set. An empty section stands for the default one.
+
+// RenameSectionStep gives a new name to a section, keeping its position. If a
+// section already has the new name, the options are moved into it.
+func RenameSectionStep(section string, newSection string) MigrationStep {
+	return func(c *Config) ([]string, error) {
+		if section == "" || section == _DEFAULT_SECTION || !c.HasSection(section) {
+			return nil, nil
+		}
+
+		if !c.HasSection(newSection) {
+			c.renameSection(section, newSection)
+		} else {
+			for _, option := range c.optionNames(section) {
+				c.moveOption(section, option, newSection, option)
+			}
+			c.RemoveSection(section)
+		}
+		return []string{"renamed section " + section + " to " + newSection}, nil
+	}
+}
+
+// RenameOptionStep gives a new name to an option, keeping its position.
+func RenameOptionStep(section string, option string, newOption string) MigrationStep {
+	return MoveOptionStep(section, option, section, newOption)
+}
+
+// MoveOptionStep moves an option to another section, possibly under a new name.
+// If an option is already set under the new name, the moved one is removed.
+func MoveOptionStep(section, option, newSection, newOption string) MigrationStep {
+	section, newSection = sectionName(section), sectionName(newSection)
+
+	return func(c *Config) ([]string, error) {
+		if _, ok := c.data[section][option]; !ok {
+			return nil, nil
+		}
+
+		c.moveOption(section, option, newSection, newOption)
+		return []string{"moved option " + option + " in section " + section +
+			" to option " + newOption + " in section " + newSection}, nil
+	}
+}
+
+// TransformValueStep replaces every (raw) value of an option by the result of
+// the given function.
+func TransformValueStep(section string, option string, transform func(value string) (string, error)) MigrationStep {
+	section = sectionName(section)
+
+	return func(c *Config) ([]string, error) {
+		values, err := c.RawValues(section, option)
+		if _, ok := c.data[section][option]; !ok || err != nil {
+			return nil, nil
+		}
+
+		changed := false
+		for i, v := range values {
+			if values[i], err = transform(v); err != nil {
+				return nil, conversionError(section, option, v, "migrated value", err)
+			}
+			changed = changed || values[i] != v
+		}
+		if !changed {
+			return nil, nil
+		}
+
+		c.SetValues(section, option, values)
+		return []string{"changed value of option " + option + " in section " + section}, nil
+	}
+}
+
+// DeleteOptionStep removes an option.
+func DeleteOptionStep(section string, option string) MigrationStep {
+	section = sectionName(section)
+
+	return func(c *Config) ([]string, error) {
+		if !c.RemoveOption(section, option) {
+			return nil, nil
+		}
+		return []string{"deleted option " + option + " in section " + section}, nil
+	}
+}
+
+// sectionName returns the name of the default section for an empty one.
+func sectionName(section string) string {
+	if section == "" {
+		return _DEFAULT_SECTION
+	}
+	return section
+}
@@ -0,0 +1,138 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+)
+
+// The accessors in this file have the same behaviour as String, so values are
+// unfolded before being parsed:
+//
+//	url: %(protocol)s%(host)s/some/path
+
+// URL has the same behaviour as String but parses the response as an absolute
+// URL, i.e. one with a scheme.
+func (self *Config) URL(section string, option string) (*url.URL, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(sv)
+	if err == nil && u.Scheme == "" {
+		err = errors.New("missing scheme")
+	}
+	if err != nil {
+		if e, ok := err.(*url.Error); ok {
+			err = e.Err
+		}
+		return nil, conversionError(section, option, sv, "URL", err)
+	}
+
+	return u, nil
+}
+
+// IP has the same behaviour as String but parses the response as an IPv4 or
+// IPv6 address.
+func (self *Config) IP(section string, option string) (net.IP, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(sv)
+	if ip == nil {
+		return nil, conversionError(section, option, sv, "IP address", nil)
+	}
+
+	return ip, nil
+}
+
+// Addr has the same behaviour as IP but returns a netip.Addr, which can also
+// hold an IPv6 zone.
+func (self *Config) Addr(section string, option string) (netip.Addr, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	addr, err := netip.ParseAddr(sv)
+	if err != nil {
+		return netip.Addr{}, conversionError(section, option, sv, "IP address", nil)
+	}
+
+	return addr, nil
+}
+
+// Prefix has the same behaviour as String but parses the response as an IP
+// network in CIDR notation, e.g. "10.0.0.0/8".
+func (self *Config) Prefix(section string, option string) (netip.Prefix, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	prefix, err := netip.ParsePrefix(sv)
+	if err != nil {
+		return netip.Prefix{}, conversionError(section, option, sv, "CIDR prefix", nil)
+	}
+
+	return prefix, nil
+}
+
+// AddrPort has the same behaviour as String but parses the response as an IP
+// address and a port, e.g. "127.0.0.1:8080" or "[::1]:8080".
+func (self *Config) AddrPort(section string, option string) (netip.AddrPort, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	ap, err := netip.ParseAddrPort(sv)
+	if err != nil {
+		return netip.AddrPort{}, conversionError(section, option, sv, "address and port", nil)
+	}
+
+	return ap, nil
+}
+
+// HostPort has the same behaviour as String but splits the response in a host
+// name (or IP address) and a numeric port, e.g. "example.com:443". IPv6
+// addresses have to be enclosed in brackets.
+func (self *Config) HostPort(section string, option string) (host string, port uint16, err error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return "", 0, err
+	}
+
+	host, sport, err := net.SplitHostPort(sv)
+	if err == nil && host == "" {
+		err = errors.New("missing host")
+	}
+	if err != nil {
+		if e, ok := err.(*net.AddrError); ok {
+			err = errors.New(e.Err)
+		}
+		return "", 0, conversionError(section, option, sv, "host and port", err)
+	}
+
+	p, err := strconv.ParseUint(sport, 10, 16)
+	if err != nil {
+		return "", 0, conversionError(section, option, sv, "host and port",
+			errors.New("invalid port"))
+	}
+
+	return host, uint16(p), nil
+}
@@ -17,18 +17,30 @@ import "errors"
 // it is created in advance.
 //
 // It returns true if the option and value were inserted, and false if the value
-// was overwritten.
+// was overwritten, in which case the option keeps its position. With
+// multi-valued options enabled (see SetMultiValue), the value is appended to
+// the existing ones instead of overwriting them.
 func (self *Config) AddOption(section string, option string, value string) bool {
+	if self.multiValue {
+		return self.AppendValue(section, option, value)
+	}
+
 	self.AddSection(section) // Make sure section exists
 
 	if section == "" {
 		section = _DEFAULT_SECTION
 	}
 
-	_, ok := self.data[section][option]
+	old, ok := self.data[section][option]
 
-	self.data[section][option] = &tValue{self.lastIdOption[section], value}
-	self.lastIdOption[section]++
+	tv := &tValue{v: value}
+	if ok {
+		tv.comment = old.comment
+	} else {
+		self.optionOrder[section].add(option)
+	}
+	self.data[section][option] = tv
+	self.changed()
 
 	return !ok
 }
@@ -42,7 +54,11 @@ func (self *Config) RemoveOption(section string, option string) bool {
 	}
 
 	_, ok := self.data[section][option]
-	delete(self.data[section], option)
+	if ok {
+		delete(self.data[section], option)
+		self.optionOrder[section].remove(option)
+		self.changed()
+	}
 
 	return ok
 }
@@ -50,34 +66,165 @@ func (self *Config) RemoveOption(section string, option string) bool {
 // HasOption checks if the configuration has the given option in the section.
 // It returns false if either the option or section do not exist.
 func (self *Config) HasOption(section string, option string) bool {
+	if _, ok := self.lookup(section, option); ok {
+		return true
+	}
 	if _, ok := self.data[section]; !ok {
 		return false
 	}
 
 	_, okd := self.data[_DEFAULT_SECTION][option]
-	_, oknd := self.data[section][option]
 
-	return okd || oknd
+	return okd
 }
 
-// Options returns the list of options available in the given section.
+// Options returns the list of options available in the given section, in the
+// order of AllOptions: the options set in the section, then those inherited
+// from its parent sections and from the default section, each one once.
 // It returns an error if the section does not exist and an empty list if the
-// section is empty. Options within the default section are also included.
+// section is empty.
 func (self *Config) Options(section string) (options []string, err error) {
 	if _, ok := self.data[section]; !ok {
 		return nil, errors.New(sectionError(section).String())
 	}
 
-	options = make([]string, len(self.data[_DEFAULT_SECTION])+len(self.data[section]))
-	i := 0
-	for s, _ := range self.data[_DEFAULT_SECTION] {
-		options[i] = s
-		i++
-	}
-	for s, _ := range self.data[section] {
-		options[i] = s
-		i++
+	options = make([]string, 0, len(self.data[_DEFAULT_SECTION])+len(self.data[section]))
+	for option := range self.AllOptions(section) {
+		options = append(options, option)
 	}
 
 	return options, nil
 }
+
+// === Multi-valued options
+// ===
+
+// AppendValue adds a value to the given option, keeping the values already
+// held so that the option becomes multi-valued. The option keeps its position.
+//
+// It returns true if the option was inserted, and false if the value was
+// appended to an existing option.
+func (self *Config) AppendValue(section string, option string, value string) bool {
+	self.AddSection(section) // Make sure section exists
+
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+
+	self.changed()
+
+	tv, ok := self.data[section][option]
+	if !ok {
+		self.data[section][option] = &tValue{v: value}
+		self.optionOrder[section].add(option)
+		return true
+	}
+
+	tv.values = append(tv.all(), value)
+	tv.v = value
+
+	return false
+}
+
+// SetValues replaces all the values of the given option by the given ones
+// (as "git config --replace-all" does). The option keeps its position if it
+// already existed. An empty list of values removes the option.
+//
+// It returns true if the option was inserted, and false if it was replaced.
+func (self *Config) SetValues(section string, option string, values []string) bool {
+	if len(values) == 0 {
+		self.RemoveOption(section, option)
+		return false
+	}
+
+	self.AddSection(section) // Make sure section exists
+
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+
+	tv, ok := self.data[section][option]
+	if !ok {
+		tv = new(tValue)
+		self.data[section][option] = tv
+		self.optionOrder[section].add(option)
+	}
+
+	self.changed()
+
+	tv.v = values[len(values)-1]
+	tv.values = nil
+	if len(values) > 1 {
+		tv.values = append([]string(nil), values...)
+	}
+
+	return !ok
+}
+
+// RemoveValue removes the first occurrence of the given (raw) value from the
+// option. The option is removed when its last value is.
+//
+// It returns true if the value was removed, and false otherwise, including if
+// either the section or the option did not exist.
+func (self *Config) RemoveValue(section string, option string, value string) bool {
+	if _, ok := self.data[section]; !ok {
+		return false
+	}
+
+	tv, ok := self.data[section][option]
+	if !ok {
+		return false
+	}
+
+	values := tv.all()
+	for i, v := range values {
+		if v != value {
+			continue
+		}
+
+		values = append(values[:i:i], values[i+1:]...)
+		self.changed()
+		switch len(values) {
+		case 0:
+			self.RemoveOption(section, option)
+		case 1:
+			tv.v, tv.values = values[0], nil
+		default:
+			tv.v, tv.values = values[len(values)-1], values
+		}
+		return true
+	}
+
+	return false
+}
+
+// === Utility
+// ===
+
+// lookup returns the value held for the option in the section, resolving
+// deprecated names (see AddAlias) and inheriting from parent sections (see
+// SetHierarchical).
+func (self *Config) lookup(section string, option string) (*tValue, bool) {
+	if tv, ok := self.data[section][option]; ok {
+		return tv, true
+	}
+	if tv, ok := self.lookupAlias(section, option); ok {
+		return tv, true
+	}
+
+	for _, parent := range self.parents(section) {
+		if tv, ok := self.data[parent][option]; ok {
+			return tv, true
+		}
+	}
+	return nil, false
+}
+
+// optionNames returns the options set in the section, without those within
+// the default section, in input order.
+func (self *Config) optionNames(section string) []string {
+	if options, ok := self.optionOrder[section]; ok {
+		return options.names()
+	}
+	return nil
+}
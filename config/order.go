@@ -0,0 +1,130 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"sort"
+)
+
+// Sections and options are kept in input order, which is the order followed
+// by Sections, the iterators and WriteFile. The functions below change it.
+
+// AddSectionBefore adds a section just before another one, or moves it there
+// if it already exists.
+// It returns an error if the other section does not exist.
+func (self *Config) AddSectionBefore(section string, mark string) error {
+	return self.placeSection(section, mark, false)
+}
+
+// AddSectionAfter adds a section just after another one, or moves it there if
+// it already exists.
+// It returns an error if the other section does not exist.
+func (self *Config) AddSectionAfter(section string, mark string) error {
+	return self.placeSection(section, mark, true)
+}
+
+// AddOptionBefore has the same behaviour as AddOption, then places the option
+// just before another one of the section.
+// It returns an error if either the section or the other option do not exist.
+func (self *Config) AddOptionBefore(section string, option string, value string, mark string) error {
+	return self.placeOption(section, option, value, mark, false)
+}
+
+// AddOptionAfter has the same behaviour as AddOption, then places the option
+// just after another one of the section.
+// It returns an error if either the section or the other option do not exist.
+func (self *Config) AddOptionAfter(section string, option string, value string, mark string) error {
+	return self.placeOption(section, option, value, mark, true)
+}
+
+// ReorderSections places the given sections first, in the given order; the
+// other sections follow them, in their current order.
+// It returns an error, and leaves the order unchanged, if a section does not
+// exist.
+func (self *Config) ReorderSections(sections ...string) error {
+	first := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		if _, ok := self.data[section]; !ok {
+			return errors.New(sectionError(section).String())
+		}
+		first[section] = true
+	}
+
+	order := make([]string, 0, self.sectionOrder.Len())
+	for _, section := range sections {
+		if first[section] {
+			order = append(order, section)
+			first[section] = false // Listed twice
+		}
+	}
+	for _, section := range self.sectionOrder.names() {
+		if _, ok := first[section]; !ok {
+			order = append(order, section)
+		}
+	}
+	self.sectionOrder.reset(order)
+
+	return nil
+}
+
+// SortOptions sorts the options of a section with the given function, which
+// reports whether an option must be placed before another one; options which
+// compare equal keep their order. A nil function sorts them alphabetically.
+// It returns an error if the section does not exist.
+func (self *Config) SortOptions(section string, less func(a, b string) bool) error {
+	section = sectionName(section)
+	if _, ok := self.data[section]; !ok {
+		return errors.New(sectionError(section).String())
+	}
+
+	if less == nil {
+		less = func(a, b string) bool { return a < b }
+	}
+	options := self.optionOrder[section].names()
+	sort.SliceStable(options, func(i, j int) bool {
+		return less(options[i], options[j])
+	})
+	self.optionOrder[section].reset(options)
+
+	return nil
+}
+
+// === Utility
+// ===
+
+func (self *Config) placeSection(section string, mark string, after bool) error {
+	if _, ok := self.data[mark]; !ok {
+		return errors.New(sectionError(mark).String())
+	}
+	if section == "" || section == mark {
+		return nil
+	}
+
+	self.AddSection(section)
+	self.sectionOrder.place(section, mark, after)
+	return nil
+}
+
+func (self *Config) placeOption(section, option, value, mark string, after bool) error {
+	section = sectionName(section)
+	if _, ok := self.data[section]; !ok {
+		return errors.New(sectionError(section).String())
+	}
+	if _, ok := self.data[section][mark]; !ok {
+		return errors.New(optionError(mark).String())
+	}
+
+	self.AddOption(section, option, value)
+	if option != mark {
+		self.optionOrder[section].place(option, mark, after)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "container/list"
+
+// nameList holds distinct names in order, with constant time insertion,
+// removal, renaming and moving of a name.
+type nameList struct {
+	order    *list.List               // Names, in order
+	elements map[string]*list.Element // Name : element in order
+}
+
+func newNameList() *nameList {
+	return &nameList{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (self *nameList) Len() int {
+	return self.order.Len()
+}
+
+// names returns the names, in order.
+func (self *nameList) names() []string {
+	names := make([]string, 0, self.order.Len())
+	for e := self.order.Front(); e != nil; e = e.Next() {
+		names = append(names, e.Value.(string))
+	}
+	return names
+}
+
+// add adds the name after the last one, unless it is already held.
+func (self *nameList) add(name string) {
+	if _, ok := self.elements[name]; !ok {
+		self.elements[name] = self.order.PushBack(name)
+	}
+}
+
+func (self *nameList) remove(name string) {
+	if e, ok := self.elements[name]; ok {
+		self.order.Remove(e)
+		delete(self.elements, name)
+	}
+}
+
+// rename replaces a name by another one, which takes its position.
+func (self *nameList) rename(name string, newName string) {
+	if e, ok := self.elements[name]; ok {
+		e.Value = newName
+		delete(self.elements, name)
+		self.elements[newName] = e
+	}
+}
+
+// place moves, or adds, the name just before or after the mark, which must be
+// held.
+func (self *nameList) place(name string, mark string, after bool) {
+	m, ok := self.elements[mark]
+	if !ok || name == mark {
+		return
+	}
+
+	e, ok := self.elements[name]
+	switch {
+	case ok && after:
+		self.order.MoveAfter(e, m)
+	case ok:
+		self.order.MoveBefore(e, m)
+	case after:
+		self.elements[name] = self.order.InsertAfter(name, m)
+	default:
+		self.elements[name] = self.order.InsertBefore(name, m)
+	}
+}
+
+// reset replaces the names by the given ones.
+func (self *nameList) reset(names []string) {
+	self.order.Init()
+	self.elements = make(map[string]*list.Element, len(names))
+	for _, name := range names {
+		self.add(name)
+	}
+}
+
+func (self *nameList) clone() *nameList {
+	c := newNameList()
+	for e := self.order.Front(); e != nil; e = e.Next() {
+		c.add(e.Value.(string))
+	}
+	return c
+}
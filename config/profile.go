@@ -0,0 +1,143 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Sections and options can be qualified with a profile name, such as "dev" or
+// "prod", after an "@" sign, to keep the values of several environments in a
+// single file:
+//
+//	[database]
+//	host: localhost
+//	port: 5432
+//	host@prod: db.example.com
+//
+//	[database@staging]
+//	host: staging.example.com
+//
+// A profile name starts with a letter, followed by letters, digits, '_' or
+// '-'; a name such as "bob@example.com" is not qualified. Qualified entries are
+// ordinary sections and options until the configuration is activated for a
+// profile (see Activate).
+
+// Separator between a name and its profile.
+const PROFILE_SEPARATOR = "@"
+
+// Profiles returns the names of the profiles qualifying sections or options,
+// in input order.
+func (self *Config) Profiles() (profiles []string) {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if _, profile := splitProfile(name); profile != "" && !seen[profile] {
+			seen[profile] = true
+			profiles = append(profiles, profile)
+		}
+	}
+
+	for _, section := range self.Sections() {
+		add(section)
+		for _, option := range self.optionNames(section) {
+			add(option)
+		}
+	}
+	return profiles
+}
+
+// Activate returns the effective configuration for the given profile: a copy
+// in which the entries qualified with that profile replace the unqualified
+// ones, and from which the entries qualified with other profiles are removed.
+// A qualified option takes precedence over an unqualified option of a
+// qualified section. The other settings of the configuration are kept.
+func (self *Config) Activate(profile string) *Config {
+	c := self.emptyCopy()
+
+	type key struct{ section, option string }
+	ranks := make(map[key]int) // Precedence of the value set
+
+	for _, section := range self.Sections() {
+		name, sprofile := splitProfile(section)
+		if sprofile != "" && sprofile != profile {
+			continue
+		}
+
+		c.AddSection(name)
+		if comment, ok := self.sectionComment[section]; ok && (sprofile != "" || c.sectionComment[name] == "") {
+			c.sectionComment[name] = comment
+		}
+		if parents, ok := self.sectionParents[section]; ok && (sprofile != "" || c.sectionParents[name] == nil) {
+			c.sectionParents[name] = parents
+		}
+
+		for _, option := range self.optionNames(section) {
+			oname, oprofile := splitProfile(option)
+			if oprofile != "" && oprofile != profile {
+				continue
+			}
+
+			rank := 0
+			if sprofile != "" {
+				rank++
+			}
+			if oprofile != "" {
+				rank += 2
+			}
+
+			k := key{name, oname}
+			if r, ok := ranks[k]; ok && r > rank {
+				continue
+			}
+			ranks[k] = rank
+
+			tv := self.data[section][option]
+			c.SetValues(name, oname, tv.all())
+			c.data[name][oname].comment = tv.comment
+			c.data[name][oname].line = tv.line
+		}
+	}
+
+	return c
+}
+
+// === Utility
+// ===
+
+// splitProfile splits a section or option name in the unqualified name and
+// the profile, which is empty if the name is not qualified. Only an identifier
+// after the separator is a profile, so that names such as "bob@example.com"
+// are kept as they are.
+func splitProfile(name string) (string, string) {
+	if _, _, ok := SplitSubsection(name); ok {
+		return name, ""
+	}
+
+	i := strings.LastIndex(name, PROFILE_SEPARATOR)
+	if i <= 0 || !isProfile(name[i+len(PROFILE_SEPARATOR):]) {
+		return name, ""
+	}
+	return name[:i], name[i+len(PROFILE_SEPARATOR):]
+}
+
+// isProfile reports whether the name is a valid profile name: a letter
+// followed by letters, digits, '_' or '-'.
+func isProfile(name string) bool {
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// A path names an option by its section and option names joined with a dot,
+// the section holding a subsection being written as its header:
+//
+//	service-1.url
+//	server.http.port
+//	remote "origin".url
+//
+// The option name follows the last dot which is neither escaped nor within
+// quotes, so that dotted section names need no escaping; a backslash escapes
+// the next character, e.g. a dot in an option name or a quote in a section
+// name. In a pattern, "*" matches any sequence of characters and "?" any
+// single character within a section, subsection or option name:
+//
+//	*.port
+//	remote "*".url
+
+// Match is an option matching a pattern, with its expanded value.
+type Match struct {
+	Section string
+	Option  string
+	Value   string
+}
+
+// Path returns the path naming the given option in the section, escaping the
+// characters which would be taken as separators or wildcards.
+func Path(section string, option string) string {
+	const special = `\.*?"`
+
+	name, subsection, ok := SplitSubsection(section)
+	p := escapePath(name, special)
+	if ok {
+		p += ` "` + escapePath(subsection, `\*?"`) + `"`
+	}
+	return p + "." + escapePath(option, special)
+}
+
+// SplitPath splits a path in the section and option names it refers to. It
+// returns an error if the path is malformed or holds wildcards.
+func SplitPath(p string) (section string, option string, err error) {
+	q, err := parsePath(p)
+	if err != nil {
+		return "", "", err
+	}
+	if q.wildcard {
+		return "", "", errors.New("unexpected wildcard in path: " + p)
+	}
+
+	section = q.section.literal
+	if q.subsection != nil {
+		section = Subsection(section, q.subsection.literal)
+	}
+	return section, q.option.literal, nil
+}
+
+// Query has the same behaviour as String for the option named by the given
+// path.
+func (self *Config) Query(p string) (string, error) {
+	section, option, err := SplitPath(p)
+	if err != nil {
+		return "", err
+	}
+	return self.String(section, option)
+}
+
+// QueryAll returns the options set in the sections which match the given
+// pattern, in input order, with their expanded values. A pattern without
+// wildcards matches at most one option.
+// It returns an error if the pattern is malformed, or a value could not be
+// expanded.
+func (self *Config) QueryAll(pattern string) (matches []Match, err error) {
+	q, err := parsePath(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, section := range self.Sections() {
+		if !q.matchSection(section) {
+			continue
+		}
+
+		for _, option := range self.optionNames(section) {
+			if !q.option.match(option) {
+				continue
+			}
+
+			value, err := self.String(section, option)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, Match{section, option, value})
+		}
+	}
+
+	return matches, nil
+}
+
+// === Utility
+// ===
+
+// pathQuery is a parsed path or pattern.
+type pathQuery struct {
+	section    pathComponent
+	subsection *pathComponent // nil unless the section holds a subsection
+	option     pathComponent
+	wildcard   bool // some component holds wildcards
+}
+
+// pathComponent is a name, or a pattern, within a path.
+type pathComponent struct {
+	literal  string // Name, with escapes removed
+	pattern  string // Regular expression matching the names
+	wildcard bool
+
+	re *regexp.Regexp // Compiled pattern, if wildcard
+}
+
+func (self *pathComponent) add(c byte, escaped bool) {
+	switch {
+	case !escaped && c == '*':
+		self.pattern += ".*"
+		self.wildcard = true
+	case !escaped && c == '?':
+		self.pattern += "."
+		self.wildcard = true
+	default:
+		self.pattern += regexp.QuoteMeta(string(c))
+	}
+	self.literal += string(c)
+}
+
+// compile compiles the pattern, once the component is parsed.
+func (self *pathComponent) compile() {
+	if self.wildcard {
+		self.re = regexp.MustCompile(`^(?s:` + self.pattern + `)$`)
+	}
+}
+
+func (self *pathComponent) match(name string) bool {
+	if !self.wildcard {
+		return name == self.literal
+	}
+	return self.re.MatchString(name)
+}
+
+func (self *pathQuery) matchSection(section string) bool {
+	name, subsection, ok := SplitSubsection(section)
+	if ok != (self.subsection != nil) {
+		return false
+	}
+	return self.section.match(name) && (!ok || self.subsection.match(subsection))
+}
+
+// parsePath parses a path or a pattern.
+func parsePath(p string) (*pathQuery, error) {
+	// The option name follows the last dot neither escaped nor quoted.
+	dot, quoted, escaped := -1, false, false
+	for i := 0; i < len(p); i++ {
+		switch {
+		case p[i] == '\\':
+			i++
+			escaped = i == len(p) // Nothing left to escape
+		case p[i] == '"':
+			quoted = !quoted
+		case p[i] == '.' && !quoted:
+			dot = i
+		}
+	}
+	if quoted || escaped {
+		return nil, errors.New("unterminated quote or escape in path: " + p)
+	}
+	if dot <= 0 || dot == len(p)-1 {
+		return nil, errors.New("path not valid: " + p)
+	}
+
+	q := new(pathQuery)
+	current := &q.section
+	for i := 0; i < dot; i++ {
+		switch c := p[i]; {
+		case c == '\\':
+			i++
+			current.add(p[i], true)
+		case c == '"' && current == &q.section:
+			// Subsection, after a space, up to the closing quote.
+			if !strings.HasSuffix(p[:dot], `"`) || i+1 == dot {
+				return nil, errors.New("text after subsection name in path: " + p)
+			}
+			if !strings.HasSuffix(q.section.literal, " ") {
+				return nil, errors.New("space expected before subsection name in path: " + p)
+			}
+			q.section.literal = strings.TrimRight(q.section.literal, " ")
+			q.section.pattern = strings.TrimRight(q.section.pattern, " ")
+			q.subsection = new(pathComponent)
+			current = q.subsection
+		case c == '"':
+			if i != dot-1 {
+				return nil, errors.New("text after subsection name in path: " + p)
+			}
+		default:
+			current.add(c, false)
+		}
+	}
+	for i := dot + 1; i < len(p); i++ {
+		if p[i] == '\\' {
+			i++
+			q.option.add(p[i], true)
+		} else {
+			q.option.add(p[i], false)
+		}
+	}
+
+	if q.section.literal == "" || q.option.literal == "" {
+		return nil, errors.New("path not valid: " + p)
+	}
+	q.section.compile()
+	q.option.compile()
+	q.wildcard = q.section.wildcard || q.option.wildcard
+	if q.subsection != nil {
+		q.subsection.compile()
+		q.wildcard = q.wildcard || q.subsection.wildcard
+	}
+	return q, nil
+}
+
+// escapePath escapes the special characters of a name within a path.
+func escapePath(name string, special string) string {
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		if strings.IndexByte(special, name[i]) != -1 {
+			b = append(b, '\\')
+		}
+		b = append(b, name[i])
+	}
+	return string(b)
+}
@@ -25,6 +25,7 @@ func _read(fname string, c *Config) (*Config, error) {
 		return nil, err
 	}
 
+	c.fname = fname
 	if err = c.read(bufio.NewReader(file)); err != nil {
 		return nil, err
 	}
@@ -48,10 +49,23 @@ func ReadDefault(fname string) (*Config, error) {
 	return _read(fname, NewDefault())
 }
 
+// ReadFile reads a configuration file into the configuration, adding its
+// sections and options to those already set. Unlike Read and ReadDefault, the
+// settings of the configuration apply while reading; e.g. repeated options
+// keep all their values if multi-valued options are enabled (see
+// SetMultiValue).
+func (self *Config) ReadFile(fname string) error {
+	_, err := _read(fname, self)
+	return err
+}
+
 // ===
 
 func (self *Config) read(buf *bufio.Reader) (err error) {
 	var section, option string
+	var lineno int
+	var optionIndent int // Indentation of the option line
+	var continued bool   // The previous line holds a value
 
 	for {
 		l, err := buf.ReadString('\n') // parse line-by-line
@@ -60,8 +74,12 @@ func (self *Config) read(buf *bufio.Reader) (err error) {
 		} else if err != nil {
 			return err
 		}
+		lineno++
 
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
 		l = strings.TrimSpace(l)
+		previous := continued
+		continued = false
 
 		// Switch written for readability (not performance)
 		switch {
@@ -76,8 +94,16 @@ func (self *Config) read(buf *bufio.Reader) (err error) {
 		// New section
 		case l[0] == '[' && l[len(l)-1] == ']':
 			option = "" // reset multi-line value
-			section = strings.TrimSpace(l[1 : len(l)-1])
+			var parents []string
+			section, parents, err = parseSectionHeader(l[1 : len(l)-1])
+			if err != nil {
+				return err
+			}
 			self.AddSection(section)
+			if len(parents) > 0 {
+				self.sectionParents[section] = parents
+				self.changed()
+			}
 
 		// No new section and no section defined so
 		//case section == "":
@@ -88,22 +114,62 @@ func (self *Config) read(buf *bufio.Reader) (err error) {
 			i := strings.IndexAny(l, "=:")
 
 			switch {
+			// Continuation of multi-line value, right after it and indented
+			// more than its option, so that it can hold "=" or ":" as map
+			// values do.
+			case self.indentedContinuation && previous && indent > optionIndent:
+				value := strings.TrimSpace(stripComments(l))
+				self.continueValue(section, option, value)
+				continued = true
 			// Option and value
 			case i > 0:
 				i := strings.IndexAny(l, "=:")
 				option = strings.TrimSpace(l[0:i])
+				optionIndent = indent
 				value := strings.TrimSpace(stripComments(l[i+1:]))
 				self.AddOption(section, option, value)
+				self.setLine(section, option, lineno)
+				continued = true
 			// Continuation of multi-line value
 			case section != "" && option != "":
-				prev, _ := self.RawString(section, option)
 				value := strings.TrimSpace(stripComments(l))
-				self.AddOption(section, option, prev+"\n"+value)
+				self.continueValue(section, option, value)
+				continued = true
 
 			default:
 				return errors.New("could not parse line: " + l)
 			}
 		}
 	}
+	for _, section := range self.Sections() {
+		if err = self.checkInheritance(section); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// continueValue adds a line to the last value of a multi-line option.
+func (self *Config) continueValue(section string, option string, line string) {
+	tv, ok := self.data[section][option]
+	if !ok {
+		return
+	}
+
+	tv.v += "\n" + line
+	self.changed()
+	if tv.values != nil {
+		tv.values[len(tv.values)-1] = tv.v
+	}
+}
+
+// setLine records the input line of an option, for error reporting.
+func (self *Config) setLine(section string, option string, line int) {
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	if tv, ok := self.data[section][option]; ok {
+		tv.line = line
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "errors"
+
+// RenameSection gives a new name to a section, which keeps its position, its
+// options and its comment. The sections inheriting from it follow the new
+// name, whether they declare it in their header or with the inherits option.
+//
+// It returns an error if the section does not exist or is the default one,
+// or if a section already has the new name.
+func (self *Config) RenameSection(section string, newSection string) error {
+	if err := self.checkSections(section, newSection); err != nil {
+		return err
+	}
+
+	self.renameSection(section, newSection)
+	return nil
+}
+
+// CopySection adds a copy of a section, with its options and comments, after
+// the last section.
+//
+// It returns an error if the section does not exist or if a section already
+// has the new name.
+func (self *Config) CopySection(section string, newSection string) error {
+	section = sectionName(section)
+	if _, ok := self.data[section]; !ok {
+		return errors.New(sectionError(section).String())
+	}
+	if _, ok := self.data[newSection]; ok || newSection == "" {
+		return errors.New(sectionExistsError(newSection).String())
+	}
+
+	self.AddSection(newSection)
+	for option, tv := range self.data[section] {
+		self.data[newSection][option] = tv.clone()
+	}
+	self.optionOrder[newSection] = self.optionOrder[section].clone()
+	if comment, ok := self.sectionComment[section]; ok {
+		self.sectionComment[newSection] = comment
+	}
+	if parents, ok := self.sectionParents[section]; ok {
+		self.sectionParents[newSection] = append([]string(nil), parents...)
+	}
+	self.changed()
+
+	return nil
+}
+
+// RenameOption gives a new name to an option, which keeps its position, its
+// values and its comment.
+//
+// It returns an error if either the section or the option do not exist, or if
+// an option already has the new name in the section.
+func (self *Config) RenameOption(section string, option string, newOption string) error {
+	return self.MoveOption(section, option, section, newOption)
+}
+
+// MoveOption moves an option, with its values and its comment, after the last
+// option of another section, possibly under a new name; the section is
+// created if it does not exist. An option moved within its section keeps its
+// position.
+//
+// It returns an error if either the section or the option do not exist, or if
+// an option already has the new name in the other section.
+func (self *Config) MoveOption(section, option, newSection, newOption string) error {
+	section, newSection = sectionName(section), sectionName(newSection)
+	if err := self.checkOptions(section, option, newSection, newOption); err != nil {
+		return err
+	}
+
+	self.moveOption(section, option, newSection, newOption)
+	return nil
+}
+
+// CopyOption adds a copy of an option, with its values and its comment, after
+// the last option of a section, possibly under a new name; the section is
+// created if it does not exist.
+//
+// It returns an error if either the section or the option do not exist, or if
+// an option already has the new name in the other section.
+func (self *Config) CopyOption(section, option, newSection, newOption string) error {
+	section, newSection = sectionName(section), sectionName(newSection)
+	if err := self.checkOptions(section, option, newSection, newOption); err != nil {
+		return err
+	}
+
+	self.AddSection(newSection)
+	self.data[newSection][newOption] = self.data[section][option].clone()
+	self.optionOrder[newSection].add(newOption)
+	self.changed()
+
+	return nil
+}
+
+// === Utility
+// ===
+
+// checkSections returns an error unless the section can be renamed.
+func (self *Config) checkSections(section string, newSection string) error {
+	if _, ok := self.data[section]; !ok || section == _DEFAULT_SECTION {
+		return errors.New(sectionError(section).String())
+	}
+	if _, ok := self.data[newSection]; ok || newSection == "" {
+		return errors.New(sectionExistsError(newSection).String())
+	}
+	return nil
+}
+
+// checkOptions returns an error unless the option can be moved or copied.
+func (self *Config) checkOptions(section, option, newSection, newOption string) error {
+	if _, ok := self.data[section]; !ok {
+		return errors.New(sectionError(section).String())
+	}
+	if _, ok := self.data[section][option]; !ok {
+		return errors.New(optionError(option).String())
+	}
+	if _, ok := self.data[newSection][newOption]; ok || newOption == "" {
+		return errors.New(optionExistsError(newOption).String())
+	}
+	return nil
+}
+
+// renameSection gives a new name to a section, keeping its position.
+func (self *Config) renameSection(section string, name string) {
+	self.data[name] = self.data[section]
+	self.optionOrder[name] = self.optionOrder[section]
+	self.sectionOrder.rename(section, name)
+	if comment, ok := self.sectionComment[section]; ok {
+		self.sectionComment[name] = comment
+	}
+	if parents, ok := self.sectionParents[section]; ok {
+		self.sectionParents[name] = parents
+	}
+
+	delete(self.data, section)
+	delete(self.optionOrder, section)
+	delete(self.sectionComment, section)
+	delete(self.sectionParents, section)
+
+	// Inheriting sections follow the new name, whether their parents are
+	// declared in their header or with the inherits option.
+	for s, parents := range self.sectionParents {
+		if renamed, ok := renameParent(parents, section, name); ok {
+			self.sectionParents[s] = renamed
+		}
+	}
+	for _, options := range self.data {
+		tv, ok := options[INHERITS_OPTION]
+		if !ok {
+			continue
+		}
+
+		parents, err := splitList(tv.v, self.listSeparator, true)
+		if renamed, ok := renameParent(parents, section, name); ok && err == nil {
+			tv.v = self.formatList(renamed)
+			if tv.values != nil {
+				tv.values[len(tv.values)-1] = tv.v
+			}
+		}
+	}
+	self.changed()
+}
+
+// renameParent returns a copy of the parents in which the section is renamed,
+// and false if the section is not one of them.
+func renameParent(parents []string, section string, name string) ([]string, bool) {
+	for i, p := range parents {
+		if p == section {
+			renamed := append([]string(nil), parents...)
+			renamed[i] = name
+			return renamed, true
+		}
+	}
+	return parents, false
+}
+
+// moveOption gives a new name to an option, possibly in another section. The
+// option keeps its position if it stays in the same section. If an option is
+// already set under the new name, the moved one is removed instead.
+func (self *Config) moveOption(section, option, newSection, newOption string) {
+	if section == newSection && option == newOption {
+		return
+	}
+	tv := self.data[section][option]
+
+	self.AddSection(newSection)
+	if _, ok := self.data[newSection][newOption]; ok {
+		self.RemoveOption(section, option)
+		return
+	}
+
+	if newSection == section {
+		self.optionOrder[section].rename(option, newOption)
+		delete(self.data[section], option)
+	} else {
+		self.RemoveOption(section, option)
+		self.optionOrder[newSection].add(newOption)
+	}
+	self.data[newSection][newOption] = tv
+	self.changed()
+}
@@ -0,0 +1,153 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StructSchema returns the schema of the configuration decoded into the given
+// struct by Decode, which can be a pointer. Besides the "config" key, which
+// names the section or option and can be followed by ",required", the field
+// tags can hold the "default" and "desc" (description) keys:
+//
+//	type Settings struct {
+//		Service struct {
+//			URL        *url.URL `config:"url,required" desc:"URL of the service."`
+//			MaxClients int      `config:"maxclients" default:"200"`
+//		} `config:"service-1" desc:"First service."`
+//	}
+//
+// Options of struct fields which are not sections belong to the default
+// section.
+func StructSchema(v interface{}) (*Schema, error) {
+	typ := reflect.TypeOf(v)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New("struct or pointer to struct expected")
+	}
+
+	c := NewDefault()
+	schema := new(Schema)
+	defaults := &SectionSchema{Name: _DEFAULT_SECTION}
+
+	for _, f := range c.fields(typ) {
+		if !f.section {
+			defaults.Options = append(defaults.Options, fieldOption(f, typ.FieldByIndex(f.index).Type))
+			continue
+		}
+
+		ftyp := typ.FieldByIndex(f.index).Type
+		ss := &SectionSchema{
+			Name:        f.name,
+			Description: f.tag.Get("desc"),
+			Required:    f.required(),
+		}
+		for _, sf := range c.fields(ftyp) {
+			ss.Options = append(ss.Options, fieldOption(sf, ftyp.FieldByIndex(sf.index).Type))
+		}
+		schema.Sections = append(schema.Sections, ss)
+	}
+
+	if len(defaults.Options) > 0 {
+		schema.Sections = append([]*SectionSchema{defaults}, schema.Sections...)
+	}
+
+	return schema, nil
+}
+
+// WriteSample writes a sample configuration file holding every section and
+// option of the schema, each one preceded by comments with its description,
+// type, constraints and default value. Options are set to their default value;
+// those without one are left empty, or are commented out if commentOut is
+// set. The arguments are those of WriteFile.
+func (self *Schema) WriteSample(fname string, perm os.FileMode, header string, commentOut bool) error {
+	c := NewDefault()
+
+	for _, ss := range self.Sections {
+		section := ss.Name
+		if section == "" {
+			section = _DEFAULT_SECTION
+		}
+
+		c.AddSection(section)
+		c.SetComment(section, "", ss.describe())
+
+		for _, opt := range ss.Options {
+			c.AddOption(section, opt.Name, opt.Default)
+			c.SetComment(section, opt.Name, opt.describe())
+			if commentOut && opt.Default == "" {
+				c.data[section][opt.Name].disabled = true
+			}
+		}
+	}
+
+	return c.WriteFile(fname, perm, header)
+}
+
+// === Utility
+// ===
+
+func (self *SectionSchema) describe() string {
+	lines := []string{}
+	if self.Description != "" {
+		lines = append(lines, self.Description)
+	}
+	if self.Required {
+		lines = append(lines, "(required)")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (self *OptionSchema) describe() string {
+	lines := []string{}
+	if self.Description != "" {
+		lines = append(lines, self.Description)
+	}
+
+	typ := "type: string"
+	if self.Type != nil {
+		typ = "type: " + self.Type.String()
+	}
+	if self.Required {
+		typ += " (required)"
+	}
+	lines = append(lines, typ)
+
+	if self.Range != nil {
+		lines = append(lines, "range: ["+
+			strconv.FormatFloat(self.Range.Min, 'g', -1, 64)+", "+
+			strconv.FormatFloat(self.Range.Max, 'g', -1, 64)+"]")
+	}
+	if len(self.Enum) > 0 {
+		lines = append(lines, "allowed: "+strings.Join(self.Enum, ", "))
+	}
+	if self.Default != "" {
+		lines = append(lines, "default: "+self.Default)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func fieldOption(f structField, typ reflect.Type) *OptionSchema {
+	return &OptionSchema{
+		Name:        f.name,
+		Description: f.tag.Get("desc"),
+		Required:    f.required(),
+		Type:        typ,
+		Default:     f.tag.Get("default"),
+	}
+}
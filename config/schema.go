@@ -0,0 +1,274 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema describes the sections and options allowed in a configuration, so
+// that it can be checked with Validate. Options within the default section
+// are only checked if the schema describes that section.
+type Schema struct {
+	Sections []*SectionSchema
+
+	// Sections not described by the schema are accepted.
+	AllowUnknownSections bool
+}
+
+// SectionSchema describes a section and its options.
+type SectionSchema struct {
+	Name        string
+	Description string
+	Required    bool
+	Options     []*OptionSchema
+
+	// Options not described by the schema are accepted.
+	AllowUnknownOptions bool
+}
+
+// OptionSchema describes an option and its value.
+type OptionSchema struct {
+	Name        string
+	Description string
+	Required    bool
+
+	// Type of the value, converted as Value does; nil for strings.
+	Type reflect.Type
+	// Default value, set by ApplyDefaults if not empty.
+	Default string
+	// Bounds of numeric values, if not nil.
+	Range *Range
+	// Allowed (unfolded) values, if not empty.
+	Enum []string
+}
+
+// Range holds the bounds of a numeric value, both included.
+type Range struct {
+	Min, Max float64
+}
+
+// Section returns the description of the given section, or nil.
+func (self *Schema) Section(name string) *SectionSchema {
+	for _, s := range self.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Option returns the description of the given option, or nil.
+func (self *SectionSchema) Option(name string) *OptionSchema {
+	for _, o := range self.Options {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// suggest returns an error suggesting known options close to the given one,
+// or nil.
+func (self *SectionSchema) suggest(option string) error {
+	var known []string
+	for _, o := range self.Options {
+		known = append(known, o.Name)
+	}
+
+	if suggestions := suggest(option, known); len(suggestions) > 0 {
+		return errors.New("did you mean " + strings.Join(suggestions, " or ") + "?")
+	}
+	return nil
+}
+
+// SchemaError records a violation of a schema.
+type SchemaError struct {
+	Section string
+	Option  string // Empty for errors about a whole section
+	File    string // Input file, if known
+	Line    int    // Input line, 0 if unknown
+	Reason  string // e.g. "unknown option" or "missing required option"
+	Err     error  // Underlying error, if any
+}
+
+func (self *SchemaError) Error() string {
+	s := ""
+	if self.File != "" {
+		s = self.File + ":"
+		if self.Line > 0 {
+			s += strconv.Itoa(self.Line) + ":"
+		}
+		s += " "
+	}
+
+	if self.Option != "" {
+		s += "option " + self.Option + " in "
+	}
+	s += "section " + self.Section + ": " + self.Reason
+	if self.Err != nil {
+		s += ": " + self.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (self *SchemaError) Unwrap() error {
+	return self.Err
+}
+
+// SchemaErrors holds all the violations of a schema found by Validate.
+type SchemaErrors []*SchemaError
+
+func (self SchemaErrors) Error() string {
+	s := make([]string, len(self))
+	for i, e := range self {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Validate checks the configuration against the schema: unknown sections and
+// options, missing required ones, and values of the wrong type, out of range
+// or not allowed.
+//
+// It returns nil if the configuration is valid, and a SchemaErrors with every
+// violation found otherwise.
+func (self *Config) Validate(schema *Schema) error {
+	var errs SchemaErrors
+
+	fail := func(section, option, reason string, err error) {
+		e := &SchemaError{Section: section, Option: option, File: self.fname,
+			Reason: reason, Err: err}
+		if tv, ok := self.data[section][option]; ok {
+			e.Line = tv.line
+		}
+		errs = append(errs, e)
+	}
+
+	// === Check what is set
+	for _, section := range self.Sections() {
+		ss := schema.Section(section)
+		if ss == nil {
+			if section != _DEFAULT_SECTION && !schema.AllowUnknownSections {
+				fail(section, "", "unknown section", nil)
+			}
+			continue
+		}
+
+		for _, option := range self.optionNames(section) {
+			opt := ss.Option(option)
+			if opt == nil {
+				if !ss.AllowUnknownOptions {
+					fail(section, option, "unknown option", ss.suggest(option))
+				}
+				continue
+			}
+
+			if reason, err := self.checkValue(section, opt); reason != "" {
+				fail(section, option, reason, err)
+			}
+		}
+	}
+
+	// === Check what is missing
+	for _, ss := range schema.Sections {
+		if !self.HasSection(ss.Name) {
+			if ss.Required {
+				fail(ss.Name, "", "missing required section", nil)
+			}
+			continue
+		}
+
+		for _, opt := range ss.Options {
+			if _, err := self.RawString(ss.Name, opt.Name); err == nil {
+				continue
+			}
+			if opt.Required {
+				fail(ss.Name, opt.Name, "missing required option", nil)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ApplyDefaults adds to the configuration the options which are not set and
+// have a default value in the schema, adding their sections as needed.
+func (self *Config) ApplyDefaults(schema *Schema) {
+	for _, ss := range schema.Sections {
+		for _, opt := range ss.Options {
+			if opt.Default == "" {
+				continue
+			}
+			if _, err := self.RawString(ss.Name, opt.Name); err != nil {
+				self.AddOption(ss.Name, opt.Name, opt.Default)
+			}
+		}
+	}
+}
+
+// checkValue checks the value of an option against its description. It
+// returns the reason of the violation, if any.
+func (self *Config) checkValue(section string, opt *OptionSchema) (reason string, err error) {
+	sv, err := self.String(section, opt.Name)
+	if err != nil {
+		return "invalid value", err
+	}
+
+	var number float64 // Value to check against the range
+	if opt.Type != nil {
+		rv := reflect.New(opt.Type).Elem()
+		if err = self.convert(section, opt.Name, rv); err != nil {
+			return "wrong type", err
+		}
+
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			number = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			number = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			number = rv.Float()
+		default:
+			if opt.Range != nil {
+				return "wrong type", errors.New("range given for a non-numeric type")
+			}
+		}
+	} else if opt.Range != nil {
+		if number, err = strconv.ParseFloat(sv, 64); err != nil {
+			return "wrong type", conversionError(section, opt.Name, sv, "float", err)
+		}
+	}
+
+	if opt.Range != nil && (number < opt.Range.Min || number > opt.Range.Max) {
+		return "value out of range", errors.New("not within [" +
+			strconv.FormatFloat(opt.Range.Min, 'g', -1, 64) + ", " +
+			strconv.FormatFloat(opt.Range.Max, 'g', -1, 64) + "]")
+	}
+
+	if len(opt.Enum) > 0 {
+		for _, v := range opt.Enum {
+			if v == sv {
+				return "", nil
+			}
+		}
+		return "value not allowed", errors.New(strconv.Quote(sv) +
+			" is not one of " + strings.Join(opt.Enum, ", "))
+	}
+
+	return "", nil
+}
@@ -28,9 +28,8 @@ func (self *Config) AddSection(section string) bool {
 
 	self.data[section] = make(map[string]*tValue)
 
-	// Section order
-	self.idSection[section] = self.lastIdSection
-	self.lastIdSection++
+	self.sectionOrder.add(section)
+	self.optionOrder[section] = newNameList()
 
 	return true
 }
@@ -45,13 +44,13 @@ func (self *Config) RemoveSection(section string) bool {
 		return false
 	}
 
-	for o, _ := range self.data[section] {
-		delete(self.data[section], o) // *value
-	}
 	delete(self.data, section)
 
-	delete(self.lastIdOption, section)
-	delete(self.idSection, section)
+	self.sectionOrder.remove(section)
+	delete(self.optionOrder, section)
+	self.changed()
+	delete(self.sectionComment, section)
+	delete(self.sectionParents, section)
 
 	return true
 }
@@ -64,20 +63,54 @@ func (self *Config) HasSection(section string) bool {
 	return ok
 }
 
-// Sections returns the list of sections in the configuration.
+// Sections returns the list of sections in the configuration, in input order.
 // (The default section always exists.)
 func (self *Config) Sections() (sections []string) {
-	sections = make([]string, len(self.idSection))
-	pos := 0 // Position in sections
-
-	for i := 0; i < self.lastIdSection; i++ {
-		for section, id := range self.idSection {
-			if id == i {
-				sections[pos] = section
-				pos++
-			}
+	return self.sectionOrder.names()
+}
+
+// SetComment sets the comment written before the given option, or before the
+// section header if the option is empty. The comment can hold several lines;
+// the comment character is added when writing. An empty comment removes it.
+//
+// It returns false if either the section or the option do not exist.
+func (self *Config) SetComment(section string, option string, comment string) bool {
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	if _, ok := self.data[section]; !ok {
+		return false
+	}
+
+	if option == "" {
+		if comment == "" {
+			delete(self.sectionComment, section)
+		} else {
+			self.sectionComment[section] = comment
 		}
+		return true
 	}
 
-	return sections
+	tv, ok := self.data[section][option]
+	if !ok {
+		return false
+	}
+	tv.comment = comment
+
+	return true
+}
+
+// Comment returns the comment written before the given option, or before the
+// section header if the option is empty.
+func (self *Config) Comment(section string, option string) string {
+	if section == "" {
+		section = _DEFAULT_SECTION
+	}
+	if option == "" {
+		return self.sectionComment[section]
+	}
+	if tv, ok := self.data[section][option]; ok {
+		return tv.comment
+	}
+	return ""
 }
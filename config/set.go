@@ -0,0 +1,138 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The setters in this file add options whose values are formatted in a
+// canonical way, which the matching getter reads back identically (see also
+// AddList and AddMap). They have the same behaviour as AddOption.
+
+// AddBool adds a new option whose value is a bool, written with its canonical
+// spelling, i.e. the first string of the matching list given to
+// SetBoolStrings ("true" or "false" by default).
+// It has the same behaviour as AddOption.
+func (self *Config) AddBool(section string, option string, value bool) bool {
+	return self.AddOption(section, option, self.formatBool(value))
+}
+
+// AddInt adds a new option whose value is an int, in decimal notation.
+func (self *Config) AddInt(section string, option string, value int) bool {
+	return self.AddOption(section, option, strconv.Itoa(value))
+}
+
+// AddInt64 adds a new option whose value is an int64, in decimal notation.
+func (self *Config) AddInt64(section string, option string, value int64) bool {
+	return self.AddOption(section, option, strconv.FormatInt(value, 10))
+}
+
+// AddUint64 adds a new option whose value is an uint64, in decimal notation.
+func (self *Config) AddUint64(section string, option string, value uint64) bool {
+	return self.AddOption(section, option, strconv.FormatUint(value, 10))
+}
+
+// AddFloat adds a new option whose value is a float, with the fewest digits
+// needed to represent it exactly.
+func (self *Config) AddFloat(section string, option string, value float64) bool {
+	return self.AddOption(section, option, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// AddDuration adds a new option whose value is a duration, e.g. "1h30m0s".
+func (self *Config) AddDuration(section string, option string, value time.Duration) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddTime adds a new option whose value is a time, formatted with the first
+// layout given to SetTimeLayouts (time.RFC3339 by default, in which case the
+// fractional seconds are kept).
+func (self *Config) AddTime(section string, option string, value time.Time) bool {
+	layout := self.timeLayouts[0]
+	if layout == time.RFC3339 {
+		layout = time.RFC3339Nano
+	}
+	return self.AddOption(section, option, value.Format(layout))
+}
+
+// AddByteSize adds a new option whose value is a number of bytes, written with
+// the unit giving the shortest exact value, e.g. "512MiB" or "1500MB".
+func (self *Config) AddByteSize(section string, option string, value uint64) bool {
+	return self.AddOption(section, option, formatByteSize(value))
+}
+
+// AddURL adds a new option whose value is an URL.
+func (self *Config) AddURL(section string, option string, value *url.URL) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddIP adds a new option whose value is an IP address.
+func (self *Config) AddIP(section string, option string, value net.IP) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddAddr adds a new option whose value is an IP address.
+func (self *Config) AddAddr(section string, option string, value netip.Addr) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddPrefix adds a new option whose value is an IP network in CIDR notation.
+func (self *Config) AddPrefix(section string, option string, value netip.Prefix) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddAddrPort adds a new option whose value is an IP address and a port.
+func (self *Config) AddAddrPort(section string, option string, value netip.AddrPort) bool {
+	return self.AddOption(section, option, value.String())
+}
+
+// AddHostPort adds a new option whose value is a host and a port, enclosing
+// IPv6 addresses in brackets.
+func (self *Config) AddHostPort(section string, option string, host string, port uint16) bool {
+	return self.AddOption(section, option,
+		net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+}
+
+// === Utility
+// ===
+
+func (self *Config) formatBool(value bool) string {
+	if value {
+		return self.boolTrue
+	}
+	return self.boolFalse
+}
+
+// Units tried to format byte sizes, by decreasing size.
+var byteSizeUnits = []string{"EiB", "EB", "PiB", "PB", "TiB", "TB", "GiB", "GB",
+	"MiB", "MB", "KiB", "kB"}
+
+func formatByteSize(value uint64) string {
+	if value == 0 {
+		return "0"
+	}
+
+	s := strconv.FormatUint(value, 10)
+	for _, unit := range byteSizeUnits {
+		n := byteUnits[strings.ToLower(unit)]
+		if value%n != 0 {
+			continue
+		}
+		if u := strconv.FormatUint(value/n, 10) + unit; len(u) < len(s) {
+			s = u
+		}
+	}
+	return s
+}
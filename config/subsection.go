@@ -0,0 +1,101 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// A subsection is written as in git-config files, with the subsection name in
+// quotes after the section one:
+//
+//	[remote "origin"]
+//	url: https://github.com/sbinet/go-config
+//
+// Within quotes, a backslash escapes the next character. A subsection is held
+// as a section named after its canonical header (see Subsection), so that all
+// the methods taking a section work with it:
+//
+//	c.String(config.Subsection("remote", "origin"), "url")
+
+// Subsection returns the name of the section holding the given subsection,
+// i.e. its header in canonical form: the section name followed by a space and
+// the quoted subsection name, with quotes and backslashes escaped.
+func Subsection(section string, subsection string) string {
+	if strings.ContainsAny(subsection, "\n\r") {
+		panic("subsection name not valid")
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return section + ` "` + r.Replace(subsection) + `"`
+}
+
+// SplitSubsection splits the name of a section holding a subsection in the
+// section and subsection names. It returns false if the section does not hold
+// a subsection.
+func SplitSubsection(name string) (section string, subsection string, ok bool) {
+	section, subsection, ok, err := parseHeader(name)
+	if err != nil || !ok {
+		return name, "", false
+	}
+	return section, subsection, true
+}
+
+// Subsections returns the names of the subsections of the given section, in
+// input order.
+func (self *Config) Subsections(section string) (subsections []string) {
+	for _, name := range self.Sections() {
+		if s, sub, ok := SplitSubsection(name); ok && s == section {
+			subsections = append(subsections, sub)
+		}
+	}
+	return subsections
+}
+
+// HasSubsection checks if the configuration has the given subsection.
+func (self *Config) HasSubsection(section string, subsection string) bool {
+	return self.HasSection(Subsection(section, subsection))
+}
+
+// === Utility
+// ===
+
+// parseHeader parses the content of a section header. If it holds a
+// subsection, it returns its section and subsection names and true; otherwise,
+// it returns the header as section name and false.
+func parseHeader(header string) (section string, subsection string, ok bool, err error) {
+	i := strings.IndexAny(header, " \t")
+	if i == -1 {
+		return header, "", false, nil
+	}
+
+	quoted := strings.TrimLeft(header[i:], " \t")
+	if len(quoted) == 0 || quoted[0] != '"' {
+		return header, "", false, nil // Section name with spaces
+	}
+
+	b := make([]byte, 0, len(quoted))
+	for j := 1; j < len(quoted); j++ {
+		switch quoted[j] {
+		case '\\':
+			if j++; j == len(quoted) {
+				return "", "", false, errors.New("unterminated subsection name: " + header)
+			}
+		case '"':
+			if j != len(quoted)-1 {
+				return "", "", false, errors.New("text after subsection name: " + header)
+			}
+			return header[:i], string(b), true, nil
+		}
+		b = append(b, quoted[j])
+	}
+
+	return "", "", false, errors.New("unterminated subsection name: " + header)
+}
@@ -0,0 +1,115 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import "strings"
+
+// Dotted section names, such as "server.http" and "server.grpc", can be seen
+// as a tree whose nodes are separated by dots: "server" is the parent of both
+// sections, whether it is set or not. A subsection (see Subsection) is a leaf
+// whose parent is its section. The tree is only a view: sections are still
+// stored, and written, with their full name.
+
+// SetHierarchical enables or disables the inheritance of options from parent
+// sections. When enabled, an option not set in a section is looked up in its
+// parent, then in the parent of that one, and so on, before the default
+// section; this applies to every getter, HasOption, Options and unfolding.
+func (self *Config) SetHierarchical(on bool) {
+	self.hierarchical = on
+	self.changed()
+}
+
+// Children returns the names of the direct children of the node at the given
+// path ("" for the root), in input order, i.e. the next components of the
+// dotted section names below that node.
+func (self *Config) Children(path string) (children []string) {
+	seen := make(map[string]bool)
+
+	for _, section := range self.Sections() {
+		if _, _, ok := SplitSubsection(section); ok || section == _DEFAULT_SECTION {
+			continue
+		}
+
+		rest := section
+		if path != "" {
+			if !strings.HasPrefix(section, path+".") {
+				continue
+			}
+			rest = section[len(path)+1:]
+		}
+
+		if i := strings.IndexByte(rest, '.'); i != -1 {
+			rest = rest[:i]
+		}
+		if rest != "" && !seen[rest] {
+			seen[rest] = true
+			children = append(children, rest)
+		}
+	}
+
+	return children
+}
+
+// SubConfig returns a copy of the part of the configuration rooted at the
+// given path: its sections are those below the path, with the path prefix
+// taken off their names ("server.http" becomes "http" for the path "server"),
+// and its default section holds the options of the default section and of the
+// sections on the path, the nearest ones taking precedence. The other settings
+// of the configuration are kept.
+func (self *Config) SubConfig(path string) *Config {
+	c := self.emptyCopy()
+
+	// Default section, then the sections on the path from the root.
+	nodes := []string{_DEFAULT_SECTION}
+	if path != "" {
+		components := strings.Split(path, ".")
+		for i := range components {
+			nodes = append(nodes, strings.Join(components[:i+1], "."))
+		}
+	}
+	for _, node := range nodes {
+		for _, option := range self.optionNames(node) {
+			c.SetValues(_DEFAULT_SECTION, option, self.data[node][option].all())
+		}
+	}
+
+	for _, section := range self.Sections() {
+		name := section
+		if path != "" {
+			if !strings.HasPrefix(section, path+".") {
+				continue
+			}
+			name = section[len(path)+1:]
+		} else if section == _DEFAULT_SECTION {
+			continue
+		}
+
+		c.AddSection(name)
+		for _, option := range self.optionNames(section) {
+			c.SetValues(name, option, self.data[section][option].all())
+		}
+	}
+
+	return c
+}
+
+// === Utility
+// ===
+
+// parent returns the name of the parent of a section, or an empty string.
+func parent(section string) string {
+	if s, _, ok := SplitSubsection(section); ok {
+		return s
+	}
+	if i := strings.LastIndexByte(section, '.'); i != -1 {
+		return section[:i]
+	}
+	return ""
+}
@@ -11,21 +11,24 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Bool has the same behaviour as String but converts the response to bool.
-// See "boolString" for string values converted to bool.
+// See SetBoolStrings for string values converted to bool.
 func (self *Config) Bool(section string, option string) (value bool, err error) {
 	sv, err := self.String(section, option)
 	if err != nil {
 		return false, err
 	}
 
-	value, ok := boolString[strings.ToLower(sv)]
+	value, ok := self.boolString[strings.ToLower(sv)]
 	if !ok {
-		return false, errors.New("could not parse bool value: " + sv)
+		return false, conversionError(section, option, sv, "bool", nil)
 	}
 
 	return value, nil
@@ -35,17 +38,185 @@ func (self *Config) Bool(section string, option string) (value bool, err error)
 func (self *Config) Float(section string, option string) (value float64, err error) {
 	sv, err := self.String(section, option)
 	if err == nil {
-		value, err = strconv.ParseFloat(sv, 64)
+		if value, err = strconv.ParseFloat(sv, 64); err != nil {
+			err = conversionError(section, option, sv, "float", err)
+		}
 	}
 
 	return value, err
 }
 
 // Int has the same behaviour as String but converts the response to int.
-func (self *Config) Int(section string, option string) (value int, err error) {
+// Integers are written as in Go literals, with the base prefixes "0x", "0o"
+// and "0b" and underscores between digits: "0x1F", "0o755" and "1_000_000";
+// leading zeros alone do not make a value octal, so that "010" is 10. Every
+// integer getter, list and conversion uses that syntax. Values overflowing the
+// type are reported as errors.
+func (self *Config) Int(section string, option string) (int, error) {
+	v, err := self.parseInt(section, option, strconv.IntSize, "int")
+	return int(v), err
+}
+
+// Int64 has the same behaviour as Int but converts the response to int64.
+func (self *Config) Int64(section string, option string) (int64, error) {
+	return self.parseInt(section, option, 64, "int64")
+}
+
+// Int8 has the same behaviour as Int64 but converts the response to int8.
+func (self *Config) Int8(section string, option string) (int8, error) {
+	v, err := self.parseInt(section, option, 8, "int8")
+	return int8(v), err
+}
+
+// Int16 has the same behaviour as Int64 but converts the response to int16.
+func (self *Config) Int16(section string, option string) (int16, error) {
+	v, err := self.parseInt(section, option, 16, "int16")
+	return int16(v), err
+}
+
+// Int32 has the same behaviour as Int64 but converts the response to int32.
+func (self *Config) Int32(section string, option string) (int32, error) {
+	v, err := self.parseInt(section, option, 32, "int32")
+	return int32(v), err
+}
+
+// Uint has the same behaviour as Int64 but converts the response to uint.
+func (self *Config) Uint(section string, option string) (uint, error) {
+	v, err := self.parseUint(section, option, strconv.IntSize, "uint")
+	return uint(v), err
+}
+
+// Uint8 has the same behaviour as Int64 but converts the response to uint8.
+func (self *Config) Uint8(section string, option string) (uint8, error) {
+	v, err := self.parseUint(section, option, 8, "uint8")
+	return uint8(v), err
+}
+
+// Uint16 has the same behaviour as Int64 but converts the response to uint16.
+func (self *Config) Uint16(section string, option string) (uint16, error) {
+	v, err := self.parseUint(section, option, 16, "uint16")
+	return uint16(v), err
+}
+
+// Uint32 has the same behaviour as Int64 but converts the response to uint32.
+func (self *Config) Uint32(section string, option string) (uint32, error) {
+	v, err := self.parseUint(section, option, 32, "uint32")
+	return uint32(v), err
+}
+
+// Uint64 has the same behaviour as Int64 but converts the response to uint64.
+func (self *Config) Uint64(section string, option string) (uint64, error) {
+	return self.parseUint(section, option, 64, "uint64")
+}
+
+// IntRange has the same behaviour as Int64 but also checks that the value is
+// within [min, max]. Out of bounds values are reported with a
+// *ConversionError wrapping strconv.ErrRange.
+func (self *Config) IntRange(section string, option string, min, max int64) (int64, error) {
+	v, err := self.Int64(section, option)
+	if err == nil && (v < min || v > max) {
+		sv, _ := self.String(section, option)
+		err = conversionError(section, option, sv, "int64",
+			fmt.Errorf("%w: not within [%d, %d]", strconv.ErrRange, min, max))
+	}
+	return v, err
+}
+
+// UintRange has the same behaviour as Uint64 but also checks that the value is
+// within [min, max], as IntRange does.
+func (self *Config) UintRange(section string, option string, min, max uint64) (uint64, error) {
+	v, err := self.Uint64(section, option)
+	if err == nil && (v < min || v > max) {
+		sv, _ := self.String(section, option)
+		err = conversionError(section, option, sv, "uint64",
+			fmt.Errorf("%w: not within [%d, %d]", strconv.ErrRange, min, max))
+	}
+	return v, err
+}
+
+func (self *Config) parseInt(section, option string, bits int, typ string) (int64, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := parseInteger(sv, bits)
+	if err != nil {
+		return 0, conversionError(section, option, sv, typ, err)
+	}
+	return v, nil
+}
+
+func (self *Config) parseUint(section, option string, bits int, typ string) (uint64, error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := parseUnsigned(sv, bits)
+	if err != nil {
+		return 0, conversionError(section, option, sv, typ, err)
+	}
+	return v, nil
+}
+
+// Duration has the same behaviour as String but converts the response to
+// time.Duration. The value is written as accepted by time.ParseDuration,
+// e.g. "30s", "1h30m" or "250ms".
+func (self *Config) Duration(section string, option string) (value time.Duration, err error) {
 	sv, err := self.String(section, option)
 	if err == nil {
-		value, err = strconv.Atoi(sv)
+		if value, err = time.ParseDuration(sv); err != nil {
+			err = conversionError(section, option, sv, "duration", nil)
+		}
+	}
+
+	return value, err
+}
+
+// Time has the same behaviour as String but converts the response to
+// time.Time, trying in turn every layout set with SetTimeLayouts (by default,
+// time.RFC3339 only).
+func (self *Config) Time(section string, option string) (value time.Time, err error) {
+	sv, err := self.String(section, option)
+	if err != nil {
+		return value, err
+	}
+
+	for _, layout := range self.timeLayouts {
+		if value, err = time.Parse(layout, sv); err == nil {
+			return value, nil
+		}
+	}
+
+	return value, conversionError(section, option, sv, "time", nil)
+}
+
+// SetTimeLayouts sets the layouts, as defined in the time package, accepted by
+// Time. The first one is used to format values.
+func (self *Config) SetTimeLayouts(layouts ...string) {
+	if len(layouts) == 0 {
+		panic("no time layout")
+	}
+	self.timeLayouts = layouts
+}
+
+// ByteSize has the same behaviour as String but converts the response to a
+// number of bytes. The value is a number, possibly with a fractional part,
+// followed by an optional unit:
+//
+//	B                              bytes
+//	kB, MB, GB, TB, PB, EB         powers of 1000
+//	KiB, MiB, GiB, TiB, PiB, EiB   powers of 1024
+//	K, M, G, T, P, E               powers of 1024
+//
+// Units are case-insensitive, so "512MiB", "1.5GB" and "64k" are all accepted.
+func (self *Config) ByteSize(section string, option string) (value uint64, err error) {
+	sv, err := self.String(section, option)
+	if err == nil {
+		if value, err = parseByteSize(sv); err != nil {
+			err = conversionError(section, option, sv, "byte size", err)
+		}
 	}
 
 	return value, err
@@ -53,24 +224,68 @@ func (self *Config) Int(section string, option string) (value int, err error) {
 
 // RawString gets the (raw) string value for the given option in the section.
 // The raw string value is not subjected to unfolding, which was illustrated in
-// the beginning of this documentation.
+// the beginning of this documentation. If the option is not set, its value in
+// the registry of default values is used, if any (see SetDefaults).
 //
 // It returns an error if either the section or the option do not exist.
 func (self *Config) RawString(section string, option string) (value string, err error) {
+	self.access(section, option)
+
+	if tValue, ok := self.lookup(section, option); ok {
+		return tValue.v, nil
+	}
+	if value, ok := self.defaults.get(section, option); ok {
+		return value, nil
+	}
 	if _, ok := self.data[section]; ok {
-		if tValue, ok := self.data[section][option]; ok {
-			return tValue.v, nil
-		}
 		return "", errors.New(optionError(option).String())
 	}
 	return "", errors.New(sectionError(section).String())
 }
 
+// RawValues gets all the (raw) values for the given option in the section, in
+// input order. Options with a single value return a list of one element.
+//
+// It returns an error if either the section or the option do not exist.
+func (self *Config) RawValues(section string, option string) (values []string, err error) {
+	self.access(section, option)
+
+	if tValue, ok := self.lookup(section, option); ok {
+		return append([]string(nil), tValue.all()...), nil
+	}
+
+	value, err := self.RawString(section, option)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+// Values has the same behaviour as String but returns all the values of a
+// multi-valued option, in input order, each one of them unfolded.
+func (self *Config) Values(section string, option string) (values []string, err error) {
+	raw, err := self.RawValues(section, option)
+	if err != nil {
+		return nil, err
+	}
+
+	values = make([]string, len(raw))
+	for i, v := range raw {
+		if values[i], err = self.unfold(section, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
 // String gets the string value for the given option in the section.
 // If the value needs to be unfolded (see e.g. %(host)s example in the beginning
 // of this documentation), then String does this unfolding automatically, up to
 // _DEPTH_VALUES number of iterations.
 //
+// For multi-valued options, the last value is returned (see Values).
+//
 // It returns an error if either the section or the option do not exist, or the
 // unfolding cycled.
 func (self *Config) String(section string, option string) (value string, err error) {
@@ -79,24 +294,50 @@ func (self *Config) String(section string, option string) (value string, err err
 		return "", err
 	}
 
+	return self.unfold(section, value)
+}
+
+// unfold substitutes the variables found in value by their values in the
+// given section or in the default one. Values unfolded are cached.
+func (self *Config) unfold(section string, value string) (string, error) {
+	if !strings.Contains(value, "%(") {
+		return value, nil
+	}
+
+	cached := self.accessed == nil // Tracking records the variables used.
+	if cached {
+		if v, ok := self.unfolded.get(section, value); ok {
+			return v, nil
+		}
+	}
+
+	unfolded, err := self.substitute(section, value)
+	if err == nil && cached {
+		self.unfolded.put(section, value, unfolded)
+	}
+	return unfolded, err
+}
+
+// substitute does the unfolding of unfold.
+func (self *Config) substitute(section string, value string) (string, error) {
 	var i int
 
 	for i = 0; i < _DEPTH_VALUES; i++ { // keep a sane depth
-		vr := varRegExp.FindString(value)
-		if len(vr) == 0 {
+		m := varRegExp.FindStringSubmatch(value)
+		if m == nil {
 			break
 		}
+		vr, noption := m[0], m[1]
 
-		// Take off leading '%(' and trailing ')s'
-		noption := strings.TrimLeft(vr, "%(")
-		noption = strings.TrimRight(noption, ")s")
-
-		// Search variable in default section
-		nvalue, _ := self.data[_DEFAULT_SECTION][noption]
-		if _, ok := self.data[section][noption]; ok {
-			nvalue = self.data[section][noption]
+		// Search variable in the section, then in default section
+		nvalue, ok := self.lookup(section, noption)
+		if ok {
+			self.access(section, noption)
+		} else {
+			nvalue, _ = self.lookup(_DEFAULT_SECTION, noption)
+			self.access(_DEFAULT_SECTION, noption)
 		}
-		if nvalue.v == "" {
+		if nvalue == nil || nvalue.v == "" {
 			return "", errors.New(optionError(noption).String())
 		}
 
@@ -111,3 +352,86 @@ func (self *Config) String(section string, option string) (value string, err err
 
 	return value, nil
 }
+
+// === Utility
+// ===
+
+// parseInteger parses a signed integer in the syntax described by Int.
+func parseInteger(s string, bits int) (int64, error) {
+	return strconv.ParseInt(trimZeros(s), 0, bits)
+}
+
+// parseUnsigned parses an unsigned integer in the syntax described by Int.
+func parseUnsigned(s string, bits int) (uint64, error) {
+	return strconv.ParseUint(trimZeros(s), 0, bits)
+}
+
+// trimZeros removes the leading zeros of a decimal integer, which would make
+// it octal for strconv.
+func trimZeros(s string) string {
+	sign := ""
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) > 1 && s[0] == '0' && (s[1] == '_' || s[1] >= '0' && s[1] <= '9') {
+		if s = strings.TrimLeft(s, "0_"); s == "" {
+			s = "0"
+		}
+	}
+	return sign + s
+}
+
+// Multipliers of the byte size units.
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"eb":  1e18,
+	"k":   1 << 10,
+	"m":   1 << 20,
+	"g":   1 << 30,
+	"t":   1 << 40,
+	"p":   1 << 50,
+	"e":   1 << 60,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+	"eib": 1 << 60,
+}
+
+func parseByteSize(s string) (uint64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok || i == 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	// Avoid rounding errors with integers.
+	if n, err := strconv.ParseUint(s[:i], 10, 64); err == nil {
+		if n > math.MaxUint64/unit {
+			return 0, strconv.ErrRange
+		}
+		return n * unit, nil
+	}
+
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, strconv.ErrSyntax
+	}
+	if f *= float64(unit); f >= math.MaxUint64 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(f), nil
+}
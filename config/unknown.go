@@ -0,0 +1,188 @@
+// Copyright 2010  The "goconfig" Authors
+//
+// Use of this source code is governed by the Simplified BSD License
+// that can be found in the LICENSE file.
+//
+// This software is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied. See the License
+// for more details.
+
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnknownOption records an option set in the configuration which is not known,
+// or a whole section if Option is empty.
+type UnknownOption struct {
+	Section string
+	Option  string
+	Line    int // Input line, 0 if unknown
+
+	// Known names closest to the unknown one.
+	Suggestions []string
+}
+
+func (self UnknownOption) String() string {
+	s := "section " + self.Section
+	if self.Option != "" {
+		s = "option " + self.Option + " in " + s
+	}
+	if self.Line > 0 {
+		s += " (line " + strconv.Itoa(self.Line) + ")"
+	}
+	if len(self.Suggestions) > 0 {
+		s += ": did you mean " + strings.Join(self.Suggestions, " or ") + "?"
+	}
+	return s
+}
+
+// Unknown returns the sections and options set in the configuration which are
+// not described by the schema (see also StructSchema), in input order, with
+// suggestions of known names for misspelled ones. Options within the default
+// section are only reported if the schema describes that section.
+func (self *Config) Unknown(schema *Schema) (unknown []UnknownOption) {
+	var sections []string
+	for _, ss := range schema.Sections {
+		sections = append(sections, ss.Name)
+	}
+
+	for _, section := range self.Sections() {
+		ss := schema.Section(section)
+		if ss == nil {
+			if section != _DEFAULT_SECTION {
+				unknown = append(unknown, UnknownOption{Section: section,
+					Suggestions: suggest(section, sections)})
+			}
+			continue
+		}
+
+		var options []string
+		for _, opt := range ss.Options {
+			options = append(options, opt.Name)
+		}
+
+		for _, option := range self.optionNames(section) {
+			if ss.Option(option) == nil {
+				unknown = append(unknown, self.unknownOption(section, option, options))
+			}
+		}
+	}
+
+	return unknown
+}
+
+// TrackAccess enables or disables the recording of the options looked up
+// through the getters, such as String or Int, so that those never read can be
+// found with Unused. Disabling it forgets the options recorded.
+func (self *Config) TrackAccess(on bool) {
+	if !on {
+		self.accessed = nil
+	} else if self.accessed == nil {
+		self.accessed = make(map[string]map[string]bool)
+	}
+}
+
+// Accessed reports whether the given option was looked up since access
+// tracking was enabled, whether it was set or not.
+func (self *Config) Accessed(section string, option string) bool {
+	return self.accessed[section][option]
+}
+
+// Unused returns the options set in the configuration which were not looked up
+// since access tracking was enabled (see TrackAccess), in input order, with
+// suggestions among the options looked up in vain in the same section.
+func (self *Config) Unused() (unused []UnknownOption) {
+	for _, section := range self.Sections() {
+		var missed []string // Looked up but not set
+		for option := range self.accessed[section] {
+			if _, ok := self.data[section][option]; !ok {
+				missed = append(missed, option)
+			}
+		}
+		sort.Strings(missed)
+
+		for _, option := range self.optionNames(section) {
+			if !self.accessed[section][option] {
+				unused = append(unused, self.unknownOption(section, option, missed))
+			}
+		}
+	}
+
+	return unused
+}
+
+// === Utility
+// ===
+
+// access records a look up, if access tracking is enabled.
+func (self *Config) access(section string, option string) {
+	if self.accessed == nil {
+		return
+	}
+	if _, ok := self.accessed[section]; !ok {
+		self.accessed[section] = make(map[string]bool)
+	}
+	self.accessed[section][option] = true
+}
+
+func (self *Config) unknownOption(section string, option string, known []string) UnknownOption {
+	return UnknownOption{
+		Section:     section,
+		Option:      option,
+		Line:        self.data[section][option].line,
+		Suggestions: suggest(option, known),
+	}
+}
+
+// suggest returns the known names closest to the given one, if they are close
+// enough.
+func suggest(name string, known []string) (suggestions []string) {
+	best := len(name) / 3 // Maximum distance
+	if best < 1 {
+		best = 1
+	}
+
+	for _, k := range known {
+		if k == name {
+			continue
+		}
+
+		switch d := editDistance(strings.ToLower(name), strings.ToLower(k)); {
+		case d < best:
+			best = d
+			suggestions = append(suggestions[:0], k)
+		case d == best:
+			suggestions = append(suggestions, k)
+		}
+	}
+
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -11,6 +11,7 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
@@ -19,18 +20,26 @@ import (
 // WriteFile saves the configuration representation to a file.
 // The desired file permissions must be passed as in os.Open. The header is a
 // string that is saved as a comment in the first line of the file.
+//
+// The configuration is formatted before the file is opened, so that the file
+// is left unchanged if it cannot be, e.g. for a section name not valid.
 func (self *Config) WriteFile(fname string, perm os.FileMode, header string) error {
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	if err := self.write(buf, header); err != nil {
+		return err
+	}
+	buf.Flush()
+
 	file, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return err
 	}
 
-	buf := bufio.NewWriter(file)
-	if err = self.write(buf, header); err != nil {
+	if _, err = file.Write(b.Bytes()); err != nil {
+		file.Close()
 		return err
 	}
-	buf.Flush()
-
 	return file.Close()
 }
 
@@ -46,32 +55,51 @@ func (self *Config) write(buf *bufio.Writer, header string) (err error) {
 		}
 	}
 
-	for _, orderedSection := range self.Sections() {
-		for section, sectionMap := range self.data {
-			if section == orderedSection {
+	for _, section := range self.sectionOrder.names() {
+		sectionMap := self.data[section]
 
-				// Skip default section if empty.
-				if section == _DEFAULT_SECTION && len(sectionMap) == 0 {
-					continue
-				}
+		// Skip default section if empty.
+		if section == _DEFAULT_SECTION && len(sectionMap) == 0 {
+			continue
+		}
+
+		header, err := self.header(section)
+		if err != nil {
+			return err
+		}
+		if _, err = buf.WriteString("\n" + self.commentLines(self.sectionComment[section]) +
+			header + "\n"); err != nil {
+			return err
+		}
+
+		// Follow the input order in options.
+		for i, option := range self.optionOrder[section].names() {
+			tValue := sectionMap[option]
 
-				if _, err = buf.WriteString("\n[" + section + "]\n"); err != nil {
+			// Separate commented options.
+			if tValue.comment != "" && i > 0 {
+				if _, err = buf.WriteString("\n"); err != nil {
 					return err
 				}
+			}
+
+			if _, err = buf.WriteString(self.commentLines(tValue.comment)); err != nil {
+				return err
+			}
 
-				// Follow the input order in options.
-				for i := 0; i < self.lastIdOption[section]; i++ {
-					for option, tValue := range sectionMap {
-
-						if tValue.position == i {
-							if _, err = buf.WriteString(fmt.Sprint(
-								option, self.separator, tValue.v, "\n")); err != nil {
-								return err
-							}
-							self.RemoveOption(section, option)
-							break
-						}
-					}
+			prefix := ""
+			if tValue.disabled {
+				prefix = self.comment
+			}
+
+			// One line per value for multi-valued options; the lines after
+			// the first of a multi-line value are indented, so that they are
+			// read back as its continuation.
+			for _, v := range tValue.all() {
+				v = strings.Replace(v, "\n", "\n"+prefix+"\t", -1)
+				if _, err = buf.WriteString(fmt.Sprint(
+					prefix, option, self.separator, v, "\n")); err != nil {
+					return err
 				}
 			}
 		}
@@ -83,3 +111,12 @@ func (self *Config) write(buf *bufio.Writer, header string) (err error) {
 
 	return nil
 }
+
+// commentLines returns the comment with the comment character added at the
+// beginning of each line, or an empty string.
+func (self *Config) commentLines(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return self.comment + strings.Replace(comment, "\n", "\n"+self.comment, -1) + "\n"
+}